@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// scopeRead allows searching and reading documentation
+	scopeRead = "read"
+	// scopeAdmin additionally allows syncing and index maintenance
+	scopeAdmin = "admin"
+)
+
+// toolScopes lists tools that need more than scopeRead.
+var toolScopes = map[string]string{
+	"sync_documentation":    scopeAdmin,
+	"get_index_diagnostics": scopeAdmin,
+	"rollback_index":        scopeAdmin,
+}
+
+type apiKeyContextKey struct{}
+
+// loadAPIKeys merges the inline keys with those from the secrets file. Keys
+// without scopes get scopeRead. Names must be unique since rate limits and
+// logs tell keys apart by name.
+func loadAPIKeys(auth AuthConfig) ([]APIKey, error) {
+	keys := append([]APIKey(nil), auth.Keys...)
+
+	if auth.KeysFile != "" {
+		data, err := os.ReadFile(auth.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys file: %w", err)
+		}
+		var secrets AuthConfig
+		if err := yaml.Unmarshal(data, &secrets); err != nil {
+			return nil, fmt.Errorf("failed to parse keys file %s: %w", auth.KeysFile, err)
+		}
+		keys = append(keys, secrets.Keys...)
+	}
+
+	seen := make(map[string]bool, len(keys))
+	names := make(map[string]bool, len(keys))
+	for i := range keys {
+		key := &keys[i]
+		key.Name = strings.TrimSpace(key.Name)
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d has no name", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("API key name %q is used by more than one key", key.Name)
+		}
+		names[key.Name] = true
+		if key.Key == "" {
+			return nil, fmt.Errorf("API key %q has no key value", key.Name)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("API key %q is configured more than once", key.Name)
+		}
+		seen[key.Key] = true
+
+		if len(key.Scopes) == 0 {
+			key.Scopes = []string{scopeRead}
+		}
+		for _, scope := range key.Scopes {
+			if scope != scopeRead && scope != scopeAdmin {
+				return nil, fmt.Errorf("API key %q has unknown scope %q (expected %s or %s)", key.Name, scope, scopeRead, scopeAdmin)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// hasScope reports whether the key grants scope; admin implies read.
+func (k *APIKey) hasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == scopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAPIKey rejects HTTP requests without a valid key, given either as a
+// bearer token or in the X-API-Key header, and passes the matched key on in
+// the request context for per-tool authorization.
+func requireAPIKey(keys []APIKey, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := matchAPIKey(keys, requestAPIKey(r))
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="talos-mcp"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	})
+}
+
+func requestAPIKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func matchAPIKey(keys []APIKey, presented string) *APIKey {
+	if presented == "" {
+		return nil
+	}
+
+	// Compare digests so neither the key contents nor their lengths leak
+	// through timing
+	presentedSum := sha256.Sum256([]byte(presented))
+	var matched *APIKey
+	for i := range keys {
+		sum := sha256.Sum256([]byte(keys[i].Key))
+		if subtle.ConstantTimeCompare(presentedSum[:], sum[:]) == 1 {
+			matched = &keys[i]
+		}
+	}
+	return matched
+}
+
+func apiKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+// authorizeTool checks the caller's scopes before running a tool. Calls
+// without a key in the context come over stdio or an unauthenticated
+// transport and are let through.
+func authorizeTool(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key := apiKeyFromContext(ctx)
+		if key == nil {
+			return next(ctx, request)
+		}
+
+		if scope := requiredToolScope(request.Params.Name); !key.hasScope(scope) {
+			log.Printf("Denied %s to API key %q (requires %s scope)", request.Params.Name, key.Name, scope)
+			return mcp.NewToolResultError(fmt.Sprintf("API key %q is not allowed to call %s (requires %s scope)", key.Name, request.Params.Name, scope)), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// filterToolsByScope hides tools the caller's key can't call from tools/list.
+func filterToolsByScope(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	key := apiKeyFromContext(ctx)
+	if key == nil {
+		return tools
+	}
+
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if key.hasScope(requiredToolScope(tool.Name)) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+func requiredToolScope(tool string) string {
+	if scope, ok := toolScopes[tool]; ok {
+		return scope
+	}
+	return scopeRead
+}
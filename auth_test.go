@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.yaml")
+	if err := os.WriteFile(keysFile, []byte("keys:\n  - name: team\n    key: from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     []APIKey
+		keysFile string
+		wantErr  string
+	}{
+		{name: "valid", keys: []APIKey{{Name: "team", Key: "a"}, {Name: "ops", Key: "b", Scopes: []string{scopeAdmin}}}},
+		{name: "empty name", keys: []APIKey{{Name: "team", Key: "a"}, {Name: " ", Key: "b"}}, wantErr: "API key 2 has no name"},
+		{name: "duplicate name", keys: []APIKey{{Name: "team", Key: "a"}, {Name: "team", Key: "b"}}, wantErr: `name "team" is used by more than one key`},
+		{name: "duplicate name across the keys file", keys: []APIKey{{Name: "team", Key: "a"}}, keysFile: keysFile, wantErr: `name "team" is used by more than one key`},
+		{name: "duplicate key", keys: []APIKey{{Name: "team", Key: "a"}, {Name: "ops", Key: "a"}}, wantErr: `"ops" is configured more than once`},
+		{name: "no key value", keys: []APIKey{{Name: "team"}}, wantErr: `"team" has no key value`},
+		{name: "unknown scope", keys: []APIKey{{Name: "team", Key: "a", Scopes: []string{"write"}}}, wantErr: `unknown scope "write"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := loadAPIKeys(AuthConfig{Keys: tt.keys, KeysFile: tt.keysFile})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadAPIKeys() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadAPIKeys() error = %v", err)
+			}
+			if len(keys[0].Scopes) != 1 || keys[0].Scopes[0] != scopeRead {
+				t.Errorf("key without scopes got %v, want [%s]", keys[0].Scopes, scopeRead)
+			}
+		})
+	}
+}
+
+func TestClientIDPerKey(t *testing.T) {
+	keys, err := loadAPIKeys(AuthConfig{Keys: []APIKey{{Name: "team", Key: "a"}, {Name: "ops", Key: "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range keys {
+		id := clientID(context.WithValue(context.Background(), apiKeyContextKey{}, &keys[i]))
+		if seen[id] {
+			t.Errorf("keys share the rate limit client %q", id)
+		}
+		seen[id] = true
+	}
+	if id := clientID(context.Background()); id != "anonymous" {
+		t.Errorf("clientID() without a key or session = %q, want anonymous", id)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// navigationScheme prefixes navigation tree resource URIs:
+	// talos-nav://<version>[/<group>/<nested group>...]
+	navigationScheme = "talos-nav://"
+
+	jsonMIMEType = "application/json"
+
+	navigationVersion = "version"
+	navigationGroup   = "group"
+	navigationPage    = "page"
+	navigationLink    = "link"
+)
+
+// NavigationNode is a version, group, page or external link of the
+// documentation navigation. Groups count every page beneath them, listed or
+// not, indexed or not.
+type NavigationNode struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	ID       string `json:"id,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Icon     string `json:"icon,omitempty"`
+	Expanded bool   `json:"expanded,omitempty"`
+	// Path and NotIndexed mark a page docs.json lists that extraction
+	// skipped, e.g. because its file is missing or empty
+	Path       string            `json:"path,omitempty"`
+	NotIndexed bool              `json:"not_indexed,omitempty"`
+	Pages      int               `json:"pages,omitempty"`
+	Children   []*NavigationNode `json:"children,omitempty"`
+}
+
+// buildNavigationTree lays out a version's navigation as docs.json has it
+// and joins each listed page to its indexed document by path. It returns
+// nil when docs.json doesn't list the version.
+func buildNavigationTree(nav *DocsNavigation, documents []*Document, version string) *NavigationNode {
+	byID := make(map[string]*Document)
+	for _, doc := range documents {
+		if doc.Version == version {
+			byID[doc.ID] = doc
+		}
+	}
+
+	var root *NavigationNode
+	for _, tab := range nav.Navigation.Tabs {
+		if tab.Tab != talosTab {
+			continue
+		}
+		for _, listed := range tab.Versions {
+			if listed.Version != version {
+				continue
+			}
+			if root == nil {
+				root = &NavigationNode{Type: navigationVersion, Title: version}
+			}
+			for _, group := range listed.Groups {
+				addNavigationGroup(root, group, version, byID)
+			}
+		}
+	}
+	return root
+}
+
+// addNavigationGroup appends group and everything beneath it to parent.
+// Entries DocsNavigation.Warnings reports are left out.
+func addNavigationGroup(parent *NavigationNode, group Group, version string, byID map[string]*Document) {
+	node := &NavigationNode{
+		Type:     navigationGroup,
+		Title:    group.Group,
+		Icon:     group.Icon,
+		Expanded: group.Expanded,
+	}
+	parent.Children = append(parent.Children, node)
+
+	for _, item := range group.Pages {
+		switch item.Kind {
+		case PageItemPage:
+			node.Children = append(node.Children, navigationPageNode(item.Page, version, byID))
+			node.Pages++
+		case PageItemGroup:
+			addNavigationGroup(node, *item.Group, version, byID)
+		case PageItemLink:
+			node.Children = append(node.Children, &NavigationNode{
+				Type:  navigationLink,
+				Title: item.Link.Title,
+				URL:   item.Link.Href,
+				Icon:  item.Link.Icon,
+			})
+		}
+	}
+	parent.Pages += node.Pages
+}
+
+func navigationPageNode(pagePath, version string, byID map[string]*Document) *NavigationNode {
+	doc := byID[documentID(version, pagePath)]
+	if doc == nil {
+		pagePath = normalizePagePath(pagePath)
+		return &NavigationNode{
+			Type:       navigationPage,
+			Title:      pagePath,
+			Path:       pagePath,
+			NotIndexed: true,
+		}
+	}
+	return &NavigationNode{
+		Type:  navigationPage,
+		Title: doc.Title,
+		ID:    doc.ID,
+		URI:   documentResourceURI(doc),
+		URL:   doc.URL,
+	}
+}
+
+// buildTrailNavigationTree rebuilds a version's hierarchy from the group
+// trail stored on each document, for when docs.json can't be read, e.g. an
+// index imported from a bundle without a checkout. Documents indexed before
+// the trail was recorded fall back to their section.
+func buildTrailNavigationTree(documents []*Document, version string) *NavigationNode {
+	var pages []*Document
+	for _, doc := range documents {
+		if doc.Version == version {
+			pages = append(pages, doc)
+		}
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].NavigationOrder < pages[j].NavigationOrder
+	})
+
+	root := &NavigationNode{Type: navigationVersion, Title: version}
+	for _, doc := range pages {
+		trail := doc.Navigation
+		if len(trail) == 0 && doc.Section != "" {
+			trail = []string{doc.Section}
+		}
+
+		parent := root
+		parent.Pages++
+		for _, group := range trail {
+			parent = childGroup(parent, group)
+			parent.Pages++
+		}
+		parent.Children = append(parent.Children, &NavigationNode{
+			Type:  navigationPage,
+			Title: doc.Title,
+			ID:    doc.ID,
+			URI:   documentResourceURI(doc),
+			URL:   doc.URL,
+		})
+	}
+	return root
+}
+
+// childGroup returns the named group under parent, adding it if needed.
+func childGroup(parent *NavigationNode, title string) *NavigationNode {
+	for _, child := range parent.Children {
+		if child.Type == navigationGroup && child.Title == title {
+			return child
+		}
+	}
+	group := &NavigationNode{Type: navigationGroup, Title: title}
+	parent.Children = append(parent.Children, group)
+	return group
+}
+
+// findNavigationGroup finds a group by its title, or by a trail of titles
+// separated by "/" or " > " for groups whose name repeats. The match is
+// case-insensitive and the first group in navigation order wins.
+func findNavigationGroup(root *NavigationNode, group string) *NavigationNode {
+	var want []string
+	for _, part := range strings.FieldsFunc(strings.ReplaceAll(group, " > ", "/"), func(r rune) bool { return r == '/' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			want = append(want, part)
+		}
+	}
+	if len(want) == 0 {
+		return nil
+	}
+
+	var find func(node *NavigationNode, trail []string) *NavigationNode
+	find = func(node *NavigationNode, trail []string) *NavigationNode {
+		for _, child := range node.Children {
+			if child.Type != navigationGroup {
+				continue
+			}
+			childTrail := append(append([]string(nil), trail...), child.Title)
+			if trailEndsWith(childTrail, want) {
+				return child
+			}
+			if found := find(child, childTrail); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(root, nil)
+}
+
+func trailEndsWith(trail, suffix []string) bool {
+	if len(suffix) > len(trail) {
+		return false
+	}
+	offset := len(trail) - len(suffix)
+	for i, part := range suffix {
+		if !strings.EqualFold(trail[offset+i], part) {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneNavigation drops the children of groups deeper than depth below
+// node; their page counts remain.
+func pruneNavigation(node *NavigationNode, depth int) *NavigationNode {
+	pruned := *node
+	if depth <= 0 {
+		if pruned.Type != navigationPage {
+			pruned.Children = nil
+		}
+		return &pruned
+	}
+	pruned.Children = make([]*NavigationNode, len(node.Children))
+	for i, child := range node.Children {
+		pruned.Children[i] = pruneNavigation(child, depth-1)
+	}
+	return &pruned
+}
+
+// navigationTree returns a version's navigation, rooted at group if given
+// and limited to depth levels below the root if positive.
+func (s *TalosDocMCPServer) navigationTree(version, group string, depth int) (*NavigationNode, error) {
+	version, err := s.resolveVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := s.searchEngine.ListDocuments()
+	var tree *NavigationNode
+	if s.fetcher != nil {
+		nav, err := s.fetcher.GetNavigation()
+		if err != nil {
+			log.Printf("Warning: building %s navigation from indexed documents: %v", version, err)
+		} else {
+			tree = buildNavigationTree(nav, documents, version)
+		}
+	}
+	if tree == nil {
+		tree = buildTrailNavigationTree(documents, version)
+	}
+	if group != "" {
+		found := findNavigationGroup(tree, group)
+		if found == nil {
+			var groups []string
+			for _, child := range tree.Children {
+				if child.Type == navigationGroup {
+					groups = append(groups, child.Title)
+				}
+			}
+			return nil, fmt.Errorf("no group %q in %s navigation; top-level groups: %s", group, version, strings.Join(groups, ", "))
+		}
+		tree = found
+	}
+	if depth > 0 {
+		tree = pruneNavigation(tree, depth)
+	}
+	return tree, nil
+}
+
+func (s *TalosDocMCPServer) handleBrowseDocs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	version := request.GetString("version", "")
+	group := request.GetString("group", "")
+	depth := request.GetInt("depth", 0)
+
+	tree, err := s.navigationTree(version, group, depth)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	resultJSON, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// registerNavigationResources adds the navigation tree URI template, e.g.
+// talos-nav://v1.11 or talos-nav://v1.11/Networking.
+func (s *TalosDocMCPServer) registerNavigationResources() {
+	template := mcp.NewResourceTemplate(
+		navigationScheme+"{version}{/group*}",
+		"Talos documentation navigation",
+		mcp.WithTemplateDescription("The navigation tree of a Talos version, optionally rooted at a group, with page titles, IDs and resource URIs, e.g. talos-nav://v1.11/Networking"),
+		mcp.WithTemplateMIMEType(jsonMIMEType),
+	)
+
+	s.mcpServer.AddResourceTemplate(template, s.handleReadNavigation)
+}
+
+func (s *TalosDocMCPServer) handleReadNavigation(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	version := templateArgument(request.Params.Arguments["version"])
+	group := templateArgument(request.Params.Arguments["group"])
+
+	tree, err := s.navigationTree(version, group, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	treeJSON, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal navigation: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: jsonMIMEType,
+			Text:     string(treeJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestBuildNavigationTree(t *testing.T) {
+	data, err := os.ReadFile("testdata/docs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nav DocsNavigation
+	if err := json.Unmarshal(data, &nav); err != nil {
+		t.Fatal(err)
+	}
+
+	// getting-started/install is listed but wasn't extracted
+	var documents []*Document
+	for _, page := range []struct{ path, title string }{
+		{"talos/v1.11/getting-started/quickstart", "Quickstart"},
+		{"talos/v1.11/platforms/aws", "AWS"},
+		{"talos/v1.11/platforms/metal/pxe", "PXE"},
+	} {
+		documents = append(documents, &Document{
+			ID:      documentID("v1.11", page.path),
+			Title:   page.title,
+			Path:    page.path,
+			Version: "v1.11",
+			URL:     "https://docs.siderolabs.com/" + page.path,
+		})
+	}
+	documents = append(documents, &Document{ID: "v1.10/talos/v1.10/platforms/aws", Title: "AWS", Version: "v1.10"})
+
+	tree := buildNavigationTree(&nav, documents, "v1.11")
+	if tree == nil {
+		t.Fatal("no tree for a version docs.json lists")
+	}
+	if tree.Type != navigationVersion || tree.Pages != 4 || len(tree.Children) != 2 {
+		t.Fatalf("root %s %q has %d pages and %d children, want a version with 4 pages in 2 groups", tree.Type, tree.Title, tree.Pages, len(tree.Children))
+	}
+
+	started := tree.Children[0]
+	if started.Title != "Getting Started" || started.Icon != "rocket" || started.Pages != 2 {
+		t.Errorf("group %+v, want Getting Started with its icon and 2 pages", started)
+	}
+	want := []NavigationNode{
+		{Type: navigationPage, Title: "Quickstart", ID: "v1.11/talos/v1.11/getting-started/quickstart", URI: "talos-docs://v1.11/talos/v1.11/getting-started/quickstart", URL: "https://docs.siderolabs.com/talos/v1.11/getting-started/quickstart"},
+		{Type: navigationPage, Title: "talos/v1.11/getting-started/install", Path: "talos/v1.11/getting-started/install", NotIndexed: true},
+		{Type: navigationLink, Title: "Talos on GitHub", URL: "https://github.com/siderolabs/talos"},
+		{Type: navigationLink, Title: "Image Factory", URL: "https://factory.talos.dev"},
+	}
+	if len(started.Children) != len(want) {
+		t.Fatalf("Getting Started has %d children, want %d", len(started.Children), len(want))
+	}
+	for i, child := range started.Children {
+		if !reflect.DeepEqual(*child, want[i]) {
+			t.Errorf("child %d = %+v, want %+v", i, *child, want[i])
+		}
+	}
+
+	// Malformed entries are left out, the nested group keeps its settings
+	platforms := tree.Children[1]
+	if platforms.Pages != 2 || len(platforms.Children) != 2 {
+		t.Fatalf("Platforms has %d pages and %d children, want 2 and 2", platforms.Pages, len(platforms.Children))
+	}
+	metal := platforms.Children[1]
+	if metal.Type != navigationGroup || metal.Title != "Bare Metal" || !metal.Expanded || metal.Pages != 1 {
+		t.Errorf("nested group %+v, want expanded Bare Metal with 1 page", metal)
+	}
+	if len(metal.Children) != 1 || metal.Children[0].ID != "v1.11/talos/v1.11/platforms/metal/pxe" {
+		t.Errorf("Bare Metal children %+v, want the PXE page", metal.Children)
+	}
+	if found := findNavigationGroup(tree, "platforms/bare metal"); found != metal {
+		t.Errorf("findNavigationGroup() = %+v, want the Bare Metal group", found)
+	}
+
+	if tree := buildNavigationTree(&nav, documents, "v1.10"); tree != nil {
+		t.Errorf("built a tree for v1.10, which docs.json doesn't list: %+v", tree)
+	}
+}
+
+func TestBuildTrailNavigationTree(t *testing.T) {
+	documents := []*Document{
+		{ID: "v1.11/b", Title: "B", Version: "v1.11", Navigation: []string{"Platforms", "Bare Metal"}, NavigationOrder: 2},
+		{ID: "v1.11/a", Title: "A", Version: "v1.11", Navigation: []string{"Platforms"}, NavigationOrder: 1},
+		{ID: "v1.11/c", Title: "C", Version: "v1.11", Section: "Legacy", NavigationOrder: 3},
+	}
+
+	tree := buildTrailNavigationTree(documents, "v1.11")
+	if tree.Pages != 3 || len(tree.Children) != 2 {
+		t.Fatalf("root has %d pages and %d children, want 3 and 2", tree.Pages, len(tree.Children))
+	}
+	platforms := tree.Children[0]
+	if platforms.Title != "Platforms" || platforms.Pages != 2 || platforms.Children[0].Title != "A" || platforms.Children[1].Title != "Bare Metal" {
+		t.Errorf("Platforms group %+v doesn't follow navigation order", platforms)
+	}
+	if tree.Children[1].Title != "Legacy" {
+		t.Errorf("document without a trail filed under %q, want its section", tree.Children[1].Title)
+	}
+}
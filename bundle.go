@@ -0,0 +1,354 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// bundleFormatVersion is bumped whenever the archive layout changes in a way
+// older importers can't read.
+const bundleFormatVersion = 1
+
+const (
+	bundleManifestName = "manifest.json"
+	bundleTaxonomyName = "taxonomy.json"
+	bundleIndexDir     = "index"
+)
+
+// BundleManifest describes a prebuilt index bundle. It is the first entry of
+// the archive so importers can reject incompatible bundles before unpacking.
+type BundleManifest struct {
+	FormatVersion int       `json:"format_version"`
+	CommitSHA     string    `json:"commit_sha"`
+	CreatedAt     time.Time `json:"created_at"`
+	DocumentCount int       `json:"document_count"`
+	Versions      []string  `json:"versions"`
+	// DocumentSchema is the documentSchemaVersion of the bundled documents
+	DocumentSchema int `json:"document_schema"`
+}
+
+// ExportBundle writes the current generation's index, its document store,
+// the taxonomy and the source commit into a gzipped tar archive.
+func (se *SearchEngine) ExportBundle(w io.Writer) (*BundleManifest, error) {
+	// Pin the generation so a concurrent swap can't close it mid-archive
+	generation := se.acquireCurrent()
+	defer generation.release()
+
+	if _, err := os.Stat(filepath.Join(generation.path, documentStoreFile)); err != nil {
+		return nil, fmt.Errorf("active index has no document store, rebuild it before exporting: %w", err)
+	}
+	if len(generation.documents) == 0 {
+		return nil, fmt.Errorf("index generation %s has no documents, build the index before exporting", generation.id)
+	}
+
+	versions := make([]string, 0, len(generation.taxonomy.Versions))
+	for version := range generation.taxonomy.Versions {
+		versions = append(versions, version)
+	}
+	sortVersions(versions)
+
+	manifest := &BundleManifest{
+		FormatVersion:  bundleFormatVersion,
+		CommitSHA:      generation.info.CommitSHA,
+		CreatedAt:      time.Now().UTC(),
+		DocumentCount:  len(generation.documents),
+		Versions:       versions,
+		DocumentSchema: generation.info.DocumentSchema,
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeBundleJSON(tw, bundleManifestName, manifest); err != nil {
+		return nil, err
+	}
+	if err := writeBundleJSON(tw, bundleTaxonomyName, generation.taxonomy); err != nil {
+		return nil, err
+	}
+	if err := writeBundleDir(tw, generation.path, bundleIndexDir); err != nil {
+		return nil, fmt.Errorf("failed to archive index: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish compression: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// ImportBundle unpacks a bundle into the staging index, validates it against
+// its manifest and publishes it as a new generation.
+func (se *SearchEngine) ImportBundle(r io.Reader) (*BundleManifest, error) {
+	if se.readOnly {
+		return nil, errReadOnlyIndex
+	}
+	se.writeMu.Lock()
+	defer se.writeMu.Unlock()
+
+	// Unpack next to the index so the final rename stays on one filesystem
+	importPath := filepath.Join(se.indexPath, "import")
+	if err := os.RemoveAll(importPath); err != nil {
+		return nil, fmt.Errorf("failed to clear import directory: %w", err)
+	}
+	defer os.RemoveAll(importPath)
+
+	manifest, err := extractBundle(r, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleIndexPath := filepath.Join(importPath, bundleIndexDir)
+	documents, err := readDocumentStore(bundleIndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle document store: %w", err)
+	}
+	if len(documents) != manifest.DocumentCount {
+		return nil, fmt.Errorf("bundle manifest lists %d documents but store has %d", manifest.DocumentCount, len(documents))
+	}
+	if err := checkBundleTaxonomy(importPath, manifest, documents); err != nil {
+		return nil, err
+	}
+
+	// Replace the staging index with the bundle's index
+	stagingPath := filepath.Join(se.indexPath, "staging")
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return nil, fmt.Errorf("failed to clear staging index: %w", err)
+	}
+	if err := os.Rename(bundleIndexPath, stagingPath); err != nil {
+		return nil, fmt.Errorf("failed to stage bundle index: %w", err)
+	}
+
+	stagingIndex, err := bleve.OpenUsing(stagingPath, map[string]interface{}{"read_only": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle index: %w", err)
+	}
+	docCount, err := stagingIndex.DocCount()
+	stagingIndex.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bundle index documents: %w", err)
+	}
+	if int(docCount) != manifest.DocumentCount {
+		return nil, fmt.Errorf("bundle manifest lists %d documents but index has %d", manifest.DocumentCount, docCount)
+	}
+
+	log.Printf("Bundle validated (%d documents, commit %s), swapping in...", docCount, manifest.CommitSHA)
+	// The manifest checks above stand in for the health check: a bundle may
+	// deliberately hold fewer documents than the index it replaces
+	info := GenerationInfo{
+		CommitSHA:      manifest.CommitSHA,
+		DocumentCount:  manifest.DocumentCount,
+		BuiltAt:        manifest.CreatedAt,
+		Source:         generationSourceBundle,
+		DocumentSchema: manifest.DocumentSchema,
+	}
+	if _, err := se.promoteStaging(stagingPath, info, false); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// checkBundleTaxonomy verifies that the bundle's taxonomy and the versions in
+// its manifest describe the documents it carries, which a bundle assembled
+// from mismatched parts wouldn't.
+func checkBundleTaxonomy(importPath string, manifest *BundleManifest, documents []*Document) error {
+	data, err := os.ReadFile(filepath.Join(importPath, bundleTaxonomyName))
+	if err != nil {
+		return fmt.Errorf("bundle has no %s: %w", bundleTaxonomyName, err)
+	}
+	var taxonomy ContentTaxonomy
+	if err := json.Unmarshal(data, &taxonomy); err != nil {
+		return fmt.Errorf("failed to decode bundle %s: %w", bundleTaxonomyName, err)
+	}
+
+	expected := newContentTaxonomy()
+	for _, doc := range documents {
+		updateTaxonomy(expected, doc)
+	}
+	for _, category := range []struct {
+		name           string
+		bundle, actual map[string]bool
+	}{
+		{"versions", taxonomy.Versions, expected.Versions},
+		{"sections", taxonomy.Sections, expected.Sections},
+		{"platforms", taxonomy.Platforms, expected.Platforms},
+		{"tags", taxonomy.Tags, expected.Tags},
+	} {
+		if !sameKeys(category.bundle, category.actual) {
+			return fmt.Errorf("bundle %s lists %d %s but its documents have %d", bundleTaxonomyName, len(category.bundle), category.name, len(category.actual))
+		}
+	}
+
+	versions := make(map[string]bool, len(manifest.Versions))
+	for _, version := range manifest.Versions {
+		versions[version] = true
+	}
+	if !sameKeys(versions, expected.Versions) {
+		return fmt.Errorf("bundle manifest lists versions %s but its documents have %d versions", strings.Join(manifest.Versions, ", "), len(expected.Versions))
+	}
+	return nil
+}
+
+func sameKeys(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeBundleJSON(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s header: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func writeBundleDir(tw *tar.Writer, dir, prefix string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+}
+
+// extractBundle unpacks an archive into dest and returns its manifest. The
+// manifest must come first and match bundleFormatVersion.
+func extractBundle(r io.Reader, dest string) (*BundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("bundle is not gzip compressed: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	var manifest *BundleManifest
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("bundle entry %q escapes the bundle root", header.Name)
+		}
+
+		if manifest == nil {
+			if name != bundleManifestName {
+				return nil, fmt.Errorf("bundle must start with %s, found %s", bundleManifestName, name)
+			}
+			manifest = &BundleManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to decode bundle manifest: %w", err)
+			}
+			if manifest.FormatVersion != bundleFormatVersion {
+				return nil, fmt.Errorf("unsupported bundle format version %d (expected %d)", manifest.FormatVersion, bundleFormatVersion)
+			}
+			// The server would rebuild an outdated index at its next start
+			if manifest.DocumentSchema != documentSchemaVersion {
+				return nil, fmt.Errorf("bundle documents use schema %d but this version needs %d, export the bundle again from an up-to-date index", manifest.DocumentSchema, documentSchemaVersion)
+			}
+			continue
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(file, tr); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+			if err := file.Close(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported bundle entry type for %s", name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+	if _, err := os.Stat(filepath.Join(dest, bundleIndexDir)); err != nil {
+		return nil, fmt.Errorf("bundle has no %s directory", bundleIndexDir)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	source := t.TempDir()
+	se := openTestEngine(t, source)
+	if err := se.IndexDocuments(testDocuments(3), "abc123", nil); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+	defer se.Close()
+
+	// Export reads the published generation next to the live writer
+	reader, err := OpenSearchEngineReadOnly(source, 20, 300)
+	if err != nil {
+		t.Fatalf("failed to open index read-only: %v", err)
+	}
+	var bundle bytes.Buffer
+	manifest, err := reader.ExportBundle(&bundle)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if manifest.CommitSHA != "abc123" || manifest.DocumentCount != 3 || manifest.DocumentSchema != documentSchemaVersion {
+		t.Errorf("exported commit %q with %d documents in schema %d, want abc123 with 3 in schema %d",
+			manifest.CommitSHA, manifest.DocumentCount, manifest.DocumentSchema, documentSchemaVersion)
+	}
+
+	target := openTestEngine(t, t.TempDir())
+	defer target.Close()
+	if _, err := target.ImportBundle(bytes.NewReader(bundle.Bytes())); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	current := target.current.Load()
+	if len(current.documents) != 3 || current.info.CommitSHA != "abc123" || current.info.Source != generationSourceBundle ||
+		current.info.DocumentSchema != documentSchemaVersion {
+		t.Errorf("imported generation %+v with %d documents", current.info, len(current.documents))
+	}
+}
+
+func TestImportOutdatedBundle(t *testing.T) {
+	var bundle bytes.Buffer
+	gz := gzip.NewWriter(&bundle)
+	tw := tar.NewWriter(gz)
+	manifest := &BundleManifest{FormatVersion: bundleFormatVersion, CommitSHA: "abc123", DocumentCount: 3}
+	if err := writeBundleJSON(tw, bundleManifestName, manifest); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+
+	target := openTestEngine(t, t.TempDir())
+	defer target.Close()
+	if _, err := target.ImportBundle(&bundle); err == nil || !strings.Contains(err.Error(), "schema") {
+		t.Errorf("importing a bundle without a document schema returned %v, want a schema error", err)
+	}
+}
+
+func TestCheckBundleTaxonomy(t *testing.T) {
+	documents := testDocuments(2)
+	manifest := &BundleManifest{Versions: []string{"v1.11"}}
+
+	tests := []struct {
+		name     string
+		taxonomy string
+		versions []string
+		wantErr  bool
+	}{
+		{name: "matching", taxonomy: `{"versions": {"v1.11": true}, "sections": {"Networking": true}, "platforms": {}, "tags": {}}`},
+		{name: "extra version", taxonomy: `{"versions": {"v1.11": true, "v1.10": true}, "sections": {"Networking": true}, "platforms": {}, "tags": {}}`, wantErr: true},
+		{name: "missing section", taxonomy: `{"versions": {"v1.11": true}, "sections": {}, "platforms": {}, "tags": {}}`, wantErr: true},
+		{name: "manifest versions differ", taxonomy: `{"versions": {"v1.11": true}, "sections": {"Networking": true}, "platforms": {}, "tags": {}}`, versions: []string{"v1.10"}, wantErr: true},
+		{name: "malformed", taxonomy: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, bundleTaxonomyName), []byte(tt.taxonomy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			m := *manifest
+			if tt.versions != nil {
+				m.Versions = tt.versions
+			}
+			err := checkBundleTaxonomy(dir, &m, documents)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBundleTaxonomy() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
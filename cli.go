@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// runCommand dispatches talos-mcp subcommands.
+func runCommand(config *Config, args []string) error {
+	switch args[0] {
+	case "bundle":
+		return runBundleCommand(config, args[1:])
+	case "diagnostics":
+		return runDiagnostics(config, args[1:])
+	case "index":
+		return runIndexCommand(config, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (available: bundle, diagnostics, index)", args[0])
+	}
+}
+
+func newSearchEngine(config *Config) (*SearchEngine, error) {
+	return NewSearchEngine(
+		config.Search.IndexPath,
+		config.Search.MaxResults,
+		config.Search.SnippetLength,
+		config.Search.RetainedGenerations,
+		config.Search.MaxDocumentDrop,
+	)
+}
+
+func runBundleCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: talos-mcp bundle <export|import> [flags]")
+	}
+
+	switch args[0] {
+	case "export":
+		return runBundleExport(config, args[1:])
+	case "import":
+		return runBundleImport(config, args[1:])
+	default:
+		return fmt.Errorf("unknown bundle command %q (available: export, import)", args[0])
+	}
+}
+
+// runBundleExport writes the current index generation out as a bundle. The
+// index is opened read-only, so a running server's index can be exported.
+func runBundleExport(config *Config, args []string) error {
+	flags := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	output := flags.String("o", "talos-docs-bundle.tar.gz", "path of the bundle to write")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	searchEngine, err := OpenSearchEngineReadOnly(config.Search.IndexPath, config.Search.MaxResults, config.Search.SnippetLength)
+	if err != nil {
+		return fmt.Errorf("failed to open index: %w", err)
+	}
+	defer searchEngine.Close()
+
+	// Write to a temporary file first so a failed export never leaves a
+	// truncated bundle behind
+	tmpPath := *output + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	manifest, err := searchEngine.ExportBundle(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to export bundle: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, *output); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+
+	absPath, _ := filepath.Abs(*output)
+	log.Printf("Exported %d documents (commit %s) to %s", manifest.DocumentCount, manifest.CommitSHA, absPath)
+	return nil
+}
+
+// runBundleImport validates a bundle and swaps it in as the active index.
+func runBundleImport(config *Config, args []string) error {
+	flags := flag.NewFlagSet("bundle import", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: talos-mcp bundle import <bundle.tar.gz>")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	searchEngine, err := newSearchEngine(config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize search engine: %w", err)
+	}
+	defer searchEngine.Close()
+
+	manifest, err := searchEngine.ImportBundle(file)
+	if err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+
+	log.Printf("Imported %d documents (commit %s, built %s) into %s",
+		manifest.DocumentCount, manifest.CommitSHA, manifest.CreatedAt.Format("2006-01-02 15:04:05"), config.Search.IndexPath)
+	return nil
+}
+
+// runDiagnostics extracts the docs without indexing them and prints the
+// extraction report as JSON on stdout.
+func runDiagnostics(config *Config, args []string) error {
+	flags := flag.NewFlagSet("diagnostics", flag.ContinueOnError)
+	failOnIssues := flags.Bool("fail-on-issues", false, "exit with an error if the report lists any issue")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fetcher, err := NewDocumentationFetcher(config.Repository.URL, defaultRepoPath(), config.Repository.Branch, config.Repository.HistoryDepth, config.Repository.PublicURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize documentation fetcher: %w", err)
+	}
+
+	nav, err := fetcher.GetNavigation()
+	if err != nil {
+		return fmt.Errorf("failed to get navigation: %w", err)
+	}
+
+	_, report, err := fetcher.ExtractDocuments(nav, nil)
+	if err != nil {
+		return fmt.Errorf("failed to extract documents: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if *failOnIssues && report.IssueCount() > 0 {
+		return fmt.Errorf("extraction reported %d issues", report.IssueCount())
+	}
+	return nil
+}
+
+func runIndexCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: talos-mcp index <generations|rollback> [flags]")
+	}
+
+	switch args[0] {
+	case "generations":
+		// Read-only, so it can inspect the index of a running server
+		searchEngine, err := OpenSearchEngineReadOnly(config.Search.IndexPath, config.Search.MaxResults, config.Search.SnippetLength)
+		if err != nil {
+			return fmt.Errorf("failed to open index: %w", err)
+		}
+		defer searchEngine.Close()
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(searchEngine.ListGenerations())
+	case "rollback":
+		searchEngine, err := newSearchEngine(config)
+		if err != nil {
+			return fmt.Errorf("failed to initialize search engine: %w", err)
+		}
+		defer searchEngine.Close()
+
+		return runIndexRollback(searchEngine, args[1:])
+	default:
+		return fmt.Errorf("unknown index command %q (available: generations, rollback)", args[0])
+	}
+}
+
+// runIndexRollback points the manifest at a retained generation. It needs
+// the index's writer lock, so it fails while a server uses the index; use
+// the rollback_index tool to switch a running server.
+func runIndexRollback(searchEngine *SearchEngine, args []string) error {
+	flags := flag.NewFlagSet("index rollback", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("usage: talos-mcp index rollback [generation]")
+	}
+
+	info, err := searchEngine.Rollback(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to roll back index: %w", err)
+	}
+
+	log.Printf("Rolled back to generation %s (%d documents, commit %s, built %s)",
+		info.ID, info.DocumentCount, info.CommitSHA, info.BuiltAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	pageAdded     = "added"
+	pageRemoved   = "removed"
+	pageChanged   = "changed"
+	pageUnchanged = "unchanged"
+
+	// diffContextLines is the number of unchanged lines shown around changes
+	diffContextLines = 3
+
+	// compareSearchLimit is the number of topic matches taken from each
+	// version
+	compareSearchLimit = 10
+
+	// maxDiffLines caps each page's unified diff so one rewritten page can't
+	// crowd out the rest of the comparison
+	maxDiffLines = 300
+)
+
+// PageComparison is one page matched across two versions by its path
+// without the version segment.
+type PageComparison struct {
+	Path          string          `json:"path"`
+	Title         string          `json:"title"`
+	Status        string          `json:"status"`
+	FromID        string          `json:"from_id,omitempty"`
+	ToID          string          `json:"to_id,omitempty"`
+	FromURL       string          `json:"from_url,omitempty"`
+	ToURL         string          `json:"to_url,omitempty"`
+	Sections      []SectionChange `json:"sections,omitempty"`
+	Diff          string          `json:"diff,omitempty"`
+	DiffTruncated bool            `json:"diff_truncated,omitempty"`
+}
+
+// SectionChange is a section added, removed or changed between versions,
+// keyed by its heading trail.
+type SectionChange struct {
+	Section      string `json:"section"`
+	Status       string `json:"status"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+// ComparisonSummary counts pages and sections by status.
+type ComparisonSummary struct {
+	PagesAdded      int `json:"pages_added"`
+	PagesRemoved    int `json:"pages_removed"`
+	PagesChanged    int `json:"pages_changed"`
+	PagesUnchanged  int `json:"pages_unchanged"`
+	SectionsAdded   int `json:"sections_added"`
+	SectionsRemoved int `json:"sections_removed"`
+	SectionsChanged int `json:"sections_changed"`
+}
+
+// comparePages compares the pages at the given cross-version paths, sorted
+// by path. from and to map cross-version paths to the page in each version.
+func comparePages(paths []string, from, to map[string]*Document, unified bool) ([]*PageComparison, ComparisonSummary) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var summary ComparisonSummary
+	var pages []*PageComparison
+	for i, path := range sorted {
+		if i > 0 && path == sorted[i-1] {
+			continue
+		}
+		fromDoc, toDoc := from[path], to[path]
+		if fromDoc == nil && toDoc == nil {
+			continue
+		}
+
+		page := comparePage(path, fromDoc, toDoc, unified)
+		switch page.Status {
+		case pageAdded:
+			summary.PagesAdded++
+		case pageRemoved:
+			summary.PagesRemoved++
+		case pageChanged:
+			summary.PagesChanged++
+		default:
+			summary.PagesUnchanged++
+		}
+		for _, section := range page.Sections {
+			switch section.Status {
+			case pageAdded:
+				summary.SectionsAdded++
+			case pageRemoved:
+				summary.SectionsRemoved++
+			default:
+				summary.SectionsChanged++
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages, summary
+}
+
+// comparePage diffs one page; either document may be nil.
+func comparePage(path string, fromDoc, toDoc *Document, unified bool) *PageComparison {
+	page := &PageComparison{Path: path}
+	fromContent, toContent := "", ""
+	if fromDoc != nil {
+		page.FromID = fromDoc.ID
+		page.FromURL = fromDoc.URL
+		page.Title = fromDoc.Title
+		fromContent = fromDoc.Content
+	}
+	if toDoc != nil {
+		page.ToID = toDoc.ID
+		page.ToURL = toDoc.URL
+		page.Title = toDoc.Title
+		toContent = toDoc.Content
+	}
+
+	switch {
+	case fromDoc == nil:
+		page.Status = pageAdded
+	case toDoc == nil:
+		page.Status = pageRemoved
+	case fromContent == toContent:
+		page.Status = pageUnchanged
+		return page
+	default:
+		page.Status = pageChanged
+	}
+
+	page.Sections = compareSections(splitSections(fromContent), splitSections(toContent))
+	if unified {
+		fromName, toName := "/dev/null", "/dev/null"
+		if fromDoc != nil {
+			fromName = fromDoc.ID
+		}
+		if toDoc != nil {
+			toName = toDoc.ID
+		}
+		page.Diff, page.DiffTruncated = unifiedDiff(fromName, toName, diffLines(fromContent, toContent))
+	}
+	return page
+}
+
+// compareSections matches sections by heading trail, listing them in the
+// order of the newer page with removed sections last.
+func compareSections(fromSections, toSections []markdownSection) []SectionChange {
+	fromByKey := sectionsByKey(fromSections)
+	toKeys := make(map[string]bool, len(toSections))
+
+	var changes []SectionChange
+	for _, section := range orderedSections(toSections) {
+		toKeys[section.key] = true
+		previous, exists := fromByKey[section.key]
+		if !exists {
+			changes = append(changes, SectionChange{
+				Section:    section.name(),
+				Status:     pageAdded,
+				LinesAdded: countLines(section.Body),
+			})
+			continue
+		}
+		if previous.Body == section.Body {
+			continue
+		}
+
+		added, removed := 0, 0
+		for _, line := range diffLines(previous.Body, section.Body) {
+			switch line.op {
+			case '+':
+				added++
+			case '-':
+				removed++
+			}
+		}
+		changes = append(changes, SectionChange{
+			Section:      section.name(),
+			Status:       pageChanged,
+			LinesAdded:   added,
+			LinesRemoved: removed,
+		})
+	}
+
+	for _, section := range orderedSections(fromSections) {
+		if !toKeys[section.key] {
+			changes = append(changes, SectionChange{
+				Section:      section.name(),
+				Status:       pageRemoved,
+				LinesRemoved: countLines(section.Body),
+			})
+		}
+	}
+	return changes
+}
+
+// keyedSection is a section with a key unique within its page: the heading
+// trail, numbered when a trail repeats.
+type keyedSection struct {
+	markdownSection
+	key string
+}
+
+func (s keyedSection) name() string {
+	if s.Trail == "" {
+		return "(introduction)"
+	}
+	return s.key
+}
+
+func orderedSections(sections []markdownSection) []keyedSection {
+	seen := make(map[string]int, len(sections))
+	keyed := make([]keyedSection, 0, len(sections))
+	for _, section := range sections {
+		key := section.Trail
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s (%d)", key, n)
+		}
+		keyed = append(keyed, keyedSection{markdownSection: section, key: key})
+	}
+	return keyed
+}
+
+func sectionsByKey(sections []markdownSection) map[string]keyedSection {
+	byKey := make(map[string]keyedSection, len(sections))
+	for _, section := range orderedSections(sections) {
+		byKey[section.key] = section
+	}
+	return byKey
+}
+
+func countLines(text string) int {
+	if text == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+}
+
+// diffLine is one line of a line-level diff: ' ' unchanged, '-' only in the
+// old text, '+' only in the new one.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines computes a line-level diff using go-diff's line mode.
+func diffLines(from, to string) []diffLine {
+	dmp := diffmatchpatch.New()
+	fromRunes, toRunes, lineArray := dmp.DiffLinesToRunes(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(fromRunes, toRunes, false), lineArray)
+
+	var lines []diffLine
+	for _, diff := range diffs {
+		op := byte(' ')
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, text := range strings.SplitAfter(diff.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op: op, text: strings.TrimSuffix(text, "\n")})
+			}
+		}
+	}
+	return lines
+}
+
+// unifiedDiff formats a line diff as a unified diff with diffContextLines of
+// context, capped at maxDiffLines lines. It reports whether it was cut short.
+func unifiedDiff(fromName, toName string, lines []diffLine) (string, bool) {
+	// fromLine[i] and toLine[i] count the lines of each text before lines[i]
+	fromLine := make([]int, len(lines)+1)
+	toLine := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+		if line.op != '+' {
+			fromLine[i+1]++
+		}
+		if line.op != '-' {
+			toLine[i+1]++
+		}
+	}
+
+	var out []string
+	out = append(out, "--- "+fromName, "+++ "+toName)
+	truncated := false
+
+	for start := 0; start < len(lines); {
+		// Find the next change and extend the hunk while changes are
+		// close enough for their context to overlap
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		last := first
+		for next := first + 1; next < len(lines); next++ {
+			if lines[next].op != ' ' {
+				if next-last > 2*diffContextLines {
+					break
+				}
+				last = next
+			}
+		}
+
+		begin := max(first-diffContextLines, 0)
+		end := min(last+diffContextLines+1, len(lines))
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(fromLine[begin], fromLine[end]-fromLine[begin]),
+			hunkRange(toLine[begin], toLine[end]-toLine[begin])))
+		for _, line := range lines[begin:end] {
+			out = append(out, string(line.op)+line.text)
+		}
+
+		if len(out) > maxDiffLines {
+			out = out[:maxDiffLines]
+			truncated = true
+			break
+		}
+		start = end
+	}
+
+	if len(out) == 2 {
+		return "", false
+	}
+	return strings.Join(out, "\n") + "\n", truncated
+}
+
+// hunkRange formats a unified diff range; an empty range names the line
+// before it.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
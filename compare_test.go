@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComparePages(t *testing.T) {
+	page := func(version, path, title, content string) *Document {
+		return &Document{
+			ID:      documentID(version, "talos/"+version+"/"+path),
+			Title:   title,
+			Content: content,
+			Version: version,
+		}
+	}
+	from := map[string]*Document{
+		"talos/install":        page("v1.10", "install", "Installing", "# Installing\n\nBoot the ISO.\n\n## Steps\n\nRun talosctl.\n"),
+		"talos/networking/vip": page("v1.10", "networking/vip", "VIP", "# VIP\n\nIntro.\n\n## Setup\n\nSet the shared IP.\n"),
+		"talos/legacy":         page("v1.10", "legacy", "Legacy", "# Legacy\n\nGone.\n"),
+		"talos/quickstart":     page("v1.10", "quickstart", "Quickstart", "# Quickstart\n"),
+	}
+	to := map[string]*Document{
+		"talos/install":        page("v1.11", "install", "Installation", "# Installation\n\nBoot the ISO.\n\n## Steps\n\nRun talosctl.\n"),
+		"talos/networking/vip": page("v1.11", "networking/vip", "VIP", "# VIP\n\nIntro.\n\n## Setup\n\nSet the shared IP on every control plane node.\n"),
+		"talos/kubespan":       page("v1.11", "kubespan", "KubeSpan", "# KubeSpan\n\nMesh.\n"),
+		"talos/quickstart":     page("v1.11", "quickstart", "Quickstart", "# Quickstart\n"),
+	}
+	paths := []string{"talos/quickstart", "talos/networking/vip", "talos/legacy", "talos/install", "talos/kubespan", "talos/install", "talos/missing"}
+
+	pages, summary := comparePages(paths, from, to, true)
+
+	wantSummary := ComparisonSummary{
+		PagesAdded: 1, PagesRemoved: 1, PagesChanged: 2, PagesUnchanged: 1,
+		SectionsAdded: 3, SectionsRemoved: 3, SectionsChanged: 1,
+	}
+	if summary != wantSummary {
+		t.Errorf("summary = %+v, want %+v", summary, wantSummary)
+	}
+
+	tests := []struct {
+		path     string
+		title    string
+		status   string
+		sections []SectionChange
+		diff     string
+	}{
+		{
+			// A renamed title moves every section under the new heading
+			path:   "talos/install",
+			title:  "Installation",
+			status: pageChanged,
+			sections: []SectionChange{
+				{Section: "Installation", Status: pageAdded, LinesAdded: 4},
+				{Section: "Installation > Steps", Status: pageAdded, LinesAdded: 3},
+				{Section: "Installing", Status: pageRemoved, LinesRemoved: 4},
+				{Section: "Installing > Steps", Status: pageRemoved, LinesRemoved: 3},
+			},
+			diff: "--- v1.10/talos/v1.10/install\n+++ v1.11/talos/v1.11/install\n@@ -1,4 +1,4 @@\n-# Installing\n+# Installation\n \n Boot the ISO.\n \n",
+		},
+		{
+			path:   "talos/kubespan",
+			title:  "KubeSpan",
+			status: pageAdded,
+			sections: []SectionChange{
+				{Section: "KubeSpan", Status: pageAdded, LinesAdded: 3},
+			},
+			diff: "--- /dev/null\n+++ v1.11/talos/v1.11/kubespan\n@@ -0,0 +1,3 @@\n+# KubeSpan\n+\n+Mesh.\n",
+		},
+		{
+			path:   "talos/legacy",
+			title:  "Legacy",
+			status: pageRemoved,
+			sections: []SectionChange{
+				{Section: "Legacy", Status: pageRemoved, LinesRemoved: 3},
+			},
+			diff: "--- v1.10/talos/v1.10/legacy\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-# Legacy\n-\n-Gone.\n",
+		},
+		{
+			// Changed in place: only the edited section is listed
+			path:   "talos/networking/vip",
+			title:  "VIP",
+			status: pageChanged,
+			sections: []SectionChange{
+				{Section: "VIP > Setup", Status: pageChanged, LinesAdded: 1, LinesRemoved: 1},
+			},
+			diff: "--- v1.10/talos/v1.10/networking/vip\n+++ v1.11/talos/v1.11/networking/vip\n@@ -4,4 +4,4 @@\n \n ## Setup\n \n-Set the shared IP.\n+Set the shared IP on every control plane node.\n",
+		},
+		{
+			path:   "talos/quickstart",
+			title:  "Quickstart",
+			status: pageUnchanged,
+		},
+	}
+
+	if len(pages) != len(tests) {
+		var got []string
+		for _, page := range pages {
+			got = append(got, page.Path)
+		}
+		t.Fatalf("compared pages %v, want %d pages sorted by path without duplicates or unknown paths", got, len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			page := pages[i]
+			if page.Path != tt.path || page.Title != tt.title || page.Status != tt.status {
+				t.Fatalf("page %s %q %s, want %s %q %s", page.Path, page.Title, page.Status, tt.path, tt.title, tt.status)
+			}
+			if !reflect.DeepEqual(page.Sections, tt.sections) {
+				t.Errorf("sections = %+v, want %+v", page.Sections, tt.sections)
+			}
+			if page.Diff != tt.diff {
+				t.Errorf("diff =\n%s\nwant\n%s", page.Diff, tt.diff)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int, change map[int]string) (string, string) {
+		var from, to strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&from, "line %d\n", i)
+			if text, ok := change[i]; ok {
+				fmt.Fprintf(&to, "%s\n", text)
+			} else {
+				fmt.Fprintf(&to, "line %d\n", i)
+			}
+		}
+		return from.String(), to.String()
+	}
+
+	tests := []struct {
+		name          string
+		change        map[int]string
+		total         int
+		wantHunks     []string
+		wantTruncated bool
+	}{
+		{name: "unchanged", total: 5},
+		{name: "single change", total: 10, change: map[int]string{5: "five"}, wantHunks: []string{"@@ -2,7 +2,7 @@"}},
+		{name: "close changes share a hunk", total: 20, change: map[int]string{5: "five", 11: "eleven"}, wantHunks: []string{"@@ -2,13 +2,13 @@"}},
+		{name: "distant changes split into hunks", total: 30, change: map[int]string{5: "five", 20: "twenty"}, wantHunks: []string{"@@ -2,7 +2,7 @@", "@@ -17,7 +17,7 @@"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := lines(tt.total, tt.change)
+			diff, truncated := unifiedDiff("a", "b", diffLines(from, to))
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if len(tt.wantHunks) == 0 {
+				if diff != "" {
+					t.Errorf("diff of identical texts = %q, want none", diff)
+				}
+				return
+			}
+
+			var hunks []string
+			for _, line := range strings.Split(diff, "\n") {
+				if strings.HasPrefix(line, "@@") {
+					hunks = append(hunks, line)
+				}
+			}
+			if !reflect.DeepEqual(hunks, tt.wantHunks) {
+				t.Errorf("hunks %v, want %v in\n%s", hunks, tt.wantHunks, diff)
+			}
+		})
+	}
+
+	// Every line changed: the diff stops at maxDiffLines
+	change := make(map[int]string)
+	for i := 1; i <= maxDiffLines; i++ {
+		change[i] = fmt.Sprintf("changed %d", i)
+	}
+	from, to := lines(maxDiffLines, change)
+	diff, truncated := unifiedDiff("a", "b", diffLines(from, to))
+	if !truncated || strings.Count(diff, "\n") != maxDiffLines {
+		t.Errorf("rewritten page diff has %d lines (truncated %v), want %d and truncated", strings.Count(diff, "\n"), truncated, maxDiffLines)
+	}
+}
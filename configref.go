@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// configLookupLimit caps the fields returned by a prefix lookup.
+const configLookupLimit = 50
+
+var (
+	// headingAnchorPattern matches the explicit anchors docgen puts on
+	// headings, e.g. "## network {#Config.machine.network}"
+	headingAnchorPattern = regexp.MustCompile(`\s*\{#([^}]*)\}\s*$`)
+
+	detailsPattern = regexp.MustCompile(`(?s)<details>\s*<summary>(.*?)</summary>(.*?)</details>`)
+	breakPattern   = regexp.MustCompile(`<br\s*/?>`)
+	htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+	mdLinkPattern  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	defaultPattern = regexp.MustCompile("(?i)defaults?(?: value)?(?: is| to)?:?\\s*`([^`]+)`")
+	indexPattern   = regexp.MustCompile(`\[\d*\]`)
+
+	// configKindPattern matches the kind line of a config document example
+	configKindPattern = regexp.MustCompile(`(?m)^kind:\s*(\S+)\s*$`)
+)
+
+// ConfigField is one field of a machine configuration document as listed
+// in the configuration reference.
+type ConfigField struct {
+	Kind        string   `json:"kind"`
+	Path        string   `json:"path"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description"`
+	Values      []string `json:"values,omitempty"`
+	Examples    []string `json:"examples,omitempty"`
+	Deprecated  bool     `json:"deprecated,omitempty"`
+	Version     string   `json:"version"`
+	Section     string   `json:"section,omitempty"`
+	StartLine   int      `json:"start_line"`
+	EndLine     int      `json:"end_line"`
+}
+
+// ConfigFieldAvailability tells in which indexed versions a field path is
+// documented.
+type ConfigFieldAvailability struct {
+	Versions  []string `json:"versions"`
+	FirstSeen string   `json:"first_seen,omitempty"`
+	LastSeen  string   `json:"last_seen,omitempty"`
+	AddedIn   string   `json:"added_in,omitempty"`
+	RemovedIn string   `json:"removed_in,omitempty"`
+}
+
+// isConfigReferencePage recognises the machine configuration reference.
+func isConfigReferencePage(doc *Document) bool {
+	return strings.Contains(doc.Path, "reference/configuration")
+}
+
+// parseConfigFields reads the field tables of a configuration reference
+// page. configDocumentKind names the config document (e.g. Config or
+// NetworkRuleConfig); each section's anchor, or failing that its headings,
+// gives the path its table's fields sit under. YAML examples in a section
+// are attached to the field the section describes.
+func parseConfigFields(doc *Document) []ConfigField {
+	kind := configDocumentKind(doc)
+	var fields []ConfigField
+	examples := make(map[string][]string)
+
+	for _, section := range splitSections(doc.Content) {
+		prefix := configSectionPath(section, kind)
+		if section.Level > 0 {
+			examples[prefix] = append(examples[prefix], codeBlocks(section.Body, "yaml")...)
+		}
+
+		for _, row := range parseFieldTable(section) {
+			name := strings.Trim(row.cells["field"], "` ")
+			if name == "" {
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+
+			description := cleanCell(row.cells["description"])
+			field := ConfigField{
+				Kind:        kind,
+				Path:        path,
+				Type:        cleanCell(row.cells["type"]),
+				Description: description,
+				Values:      cellValues(row.cells["value(s)"] + row.cells["values"]),
+				Deprecated:  strings.Contains(strings.ToLower(description), "deprecated"),
+				Version:     doc.Version,
+				Section:     stripAnchors(section.Trail),
+				StartLine:   row.line,
+				EndLine:     row.line,
+			}
+			if value := strings.Trim(cleanCell(row.cells["default"]), "` "); value != "" {
+				field.Default = value
+			} else if match := defaultPattern.FindStringSubmatch(row.cells["description"]); match != nil {
+				field.Default = match[1]
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	for i := range fields {
+		// Sections about list items, e.g. interfaces[], document the list field
+		fields[i].Examples = slices.Concat(examples[fields[i].Path], examples[fields[i].Path+"[]"])
+	}
+	return fields
+}
+
+// configDocumentKind names the config document a reference page describes.
+// Generated pages have their title only in frontmatter, so the page title
+// is just the file name; the kind comes from the docgen anchors
+// ({#NetworkRuleConfig.ingress.}) or the kind line of the page's YAML
+// example instead, and the title is the last resort.
+func configDocumentKind(doc *Document) string {
+	for _, line := range strings.Split(doc.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := headingAnchorPattern.FindStringSubmatch(line); match != nil {
+			if kind := strings.SplitN(match[1], ".", 2)[0]; kind != "" {
+				return kind
+			}
+		}
+	}
+
+	for _, block := range codeBlocks(doc.Content, "yaml") {
+		if match := configKindPattern.FindStringSubmatch(block); match != nil {
+			return match[1]
+		}
+	}
+	return doc.Title
+}
+
+// configSectionPath returns the field path a section documents, e.g.
+// machine.network.interfaces[] for {#Config.machine.network.interfaces.}.
+// Sections without anchors use their headings below the document's own.
+func configSectionPath(section markdownSection, kind string) string {
+	if match := headingAnchorPattern.FindStringSubmatch(strings.SplitN(section.Body, "\n", 2)[0]); match != nil {
+		segments := strings.Split(match[1], ".")[1:]
+		var path []string
+		for _, segment := range segments {
+			if segment == "" {
+				// docgen marks array items with an empty segment
+				if len(path) > 0 {
+					path[len(path)-1] += "[]"
+				}
+				continue
+			}
+			path = append(path, segment)
+		}
+		return strings.Join(path, ".")
+	}
+
+	if section.Trail == "" {
+		return ""
+	}
+	var path []string
+	for i, heading := range strings.Split(stripAnchors(section.Trail), " > ") {
+		if i == 0 && heading == kind {
+			continue
+		}
+		path = append(path, strings.Trim(heading, "` "))
+	}
+	return strings.Join(path, ".")
+}
+
+// stripAnchors drops the explicit anchors from a heading trail.
+func stripAnchors(trail string) string {
+	headings := strings.Split(trail, " > ")
+	for i, heading := range headings {
+		headings[i] = headingAnchorPattern.ReplaceAllString(heading, "")
+	}
+	return strings.Join(headings, " > ")
+}
+
+type fieldTableRow struct {
+	cells map[string]string
+	line  int
+}
+
+// parseFieldTable returns the rows of the section's markdown tables whose
+// header has Field and Type columns, keyed by lower-case column name.
+func parseFieldTable(section markdownSection) []fieldTableRow {
+	var rows []fieldTableRow
+	var columns []string
+	for i, line := range strings.Split(section.Body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "|") {
+			columns = nil
+			continue
+		}
+
+		cells := splitTableRow(trimmed)
+		switch {
+		case columns == nil:
+			header := make([]string, len(cells))
+			for j, cell := range cells {
+				header[j] = strings.ToLower(strings.TrimSpace(cell))
+			}
+			if len(header) >= 2 && header[0] == "field" && header[1] == "type" {
+				columns = header
+			}
+		case strings.Trim(trimmed, "|-: ") == "":
+			// Separator row
+		default:
+			row := fieldTableRow{cells: make(map[string]string), line: section.StartLine + i}
+			for j, cell := range cells {
+				if j < len(columns) {
+					row.cells[columns[j]] = strings.TrimSpace(cell)
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// splitTableRow splits a markdown table row on unescaped pipes.
+func splitTableRow(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+// cleanCell turns a table cell's HTML and markdown into plain text.
+func cleanCell(cell string) string {
+	cell = detailsPattern.ReplaceAllString(cell, "$1 $2")
+	cell = breakPattern.ReplaceAllString(cell, " ")
+	cell = htmlTagPattern.ReplaceAllString(cell, "")
+	cell = mdLinkPattern.ReplaceAllString(cell, "$1")
+	return strings.Join(strings.Fields(cell), " ")
+}
+
+// cellValues splits a Value(s) cell into its allowed values.
+func cellValues(cell string) []string {
+	var values []string
+	for _, value := range breakPattern.Split(cell, -1) {
+		if value = strings.Trim(cleanCell(value), "` "); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// configFieldKey normalizes a field path for lookups: case-insensitive,
+// with array markers and indices dropped, so machine.network.interfaces[0].vip
+// finds machine.network.interfaces[].vip.
+func configFieldKey(path string) string {
+	path = indexPattern.ReplaceAllString(strings.TrimSpace(path), "")
+	return strings.ToLower(strings.Trim(path, "."))
+}
+
+// configFields returns the parsed fields of every configuration reference
+// page grouped by version, along with the pages keyed by version and kind.
+func (s *TalosDocMCPServer) configFields() (map[string][]ConfigField, map[string]*Document) {
+	byVersion := make(map[string][]ConfigField)
+	pages := make(map[string]*Document)
+	for _, doc := range s.searchEngine.ListDocuments() {
+		if !isConfigReferencePage(doc) {
+			continue
+		}
+		byVersion[doc.Version] = append(byVersion[doc.Version], doc.ConfigFields...)
+		if len(doc.ConfigFields) > 0 {
+			pages[doc.Version+"/"+doc.ConfigFields[0].Kind] = doc
+		}
+	}
+	return byVersion, pages
+}
+
+// configFieldVersions maps every field key to the versions documenting it,
+// so availability is one lookup per field instead of a scan of every
+// version's fields.
+type configFieldVersions struct {
+	// references lists the versions with a configuration reference, oldest
+	// first
+	references []string
+	keys       map[string][]string
+}
+
+func newConfigFieldVersions(byVersion map[string][]ConfigField) *configFieldVersions {
+	index := &configFieldVersions{keys: make(map[string][]string)}
+	for version := range byVersion {
+		index.references = append(index.references, version)
+	}
+	sortVersions(index.references)
+
+	for _, version := range index.references {
+		for _, field := range byVersion[version] {
+			key := configFieldKey(field.Path)
+			if versions := index.keys[key]; len(versions) == 0 || versions[len(versions)-1] != version {
+				index.keys[key] = append(versions, version)
+			}
+		}
+	}
+	return index
+}
+
+// availability reports the versions documenting key. A field first seen in
+// the oldest version with a configuration reference may be older still, so
+// it gets no added_in.
+func (index *configFieldVersions) availability(key string) ConfigFieldAvailability {
+	availability := ConfigFieldAvailability{Versions: append([]string{}, index.keys[key]...)}
+	if len(availability.Versions) == 0 {
+		return availability
+	}
+
+	availability.FirstSeen = availability.Versions[0]
+	availability.LastSeen = availability.Versions[len(availability.Versions)-1]
+	if availability.FirstSeen != index.references[0] {
+		availability.AddedIn = availability.FirstSeen
+	}
+	for i, version := range index.references {
+		if version == availability.LastSeen && i+1 < len(index.references) {
+			availability.RemovedIn = index.references[i+1]
+		}
+	}
+	return availability
+}
+
+func (s *TalosDocMCPServer) handleLookupConfigField(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+	version, err := s.resolveVersion(request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	prefix := request.GetBool("prefix", false)
+
+	byVersion, pages := s.configFields()
+	if len(byVersion[version]) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no machine configuration reference is indexed for %s", version)), nil
+	}
+
+	key := configFieldKey(path)
+	type fieldResult struct {
+		ConfigField
+		Availability ConfigFieldAvailability `json:"availability"`
+		Source       Citation                `json:"source"`
+	}
+
+	var found []ConfigField
+	for _, field := range byVersion[version] {
+		fieldKey := configFieldKey(field.Path)
+		if fieldKey == key || (prefix && (key == "" || strings.HasPrefix(fieldKey, key+"."))) {
+			found = append(found, field)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Path < found[j].Path
+	})
+	total := len(found)
+	truncated := total > configLookupLimit
+	if truncated {
+		found = found[:configLookupLimit]
+	}
+
+	versions := newConfigFieldVersions(byVersion)
+	matches := []fieldResult{}
+	for _, field := range found {
+		matches = append(matches, fieldResult{
+			ConfigField:  field,
+			Availability: versions.availability(configFieldKey(field.Path)),
+			Source:       configFieldCitation(pages[field.Version+"/"+field.Kind], field),
+		})
+	}
+
+	result := map[string]interface{}{
+		"path":      path,
+		"version":   version,
+		"prefix":    prefix,
+		"total":     total,
+		"fields":    matches,
+		"truncated": truncated,
+	}
+	if len(matches) == 0 {
+		// Say where the field does exist, e.g. when it was removed
+		availability := versions.availability(key)
+		result["availability"] = availability
+		if len(availability.Versions) > 0 {
+			result["message"] = fmt.Sprintf("%s is not documented in %s; it is documented in %s", path, version, strings.Join(availability.Versions, ", "))
+		} else {
+			result["message"] = fmt.Sprintf("%s is not documented in any indexed version", path)
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// configFieldCitation cites the table row a field was parsed from.
+func configFieldCitation(doc *Document, field ConfigField) Citation {
+	return Citation{
+		DocumentID: doc.ID,
+		Title:      doc.Title,
+		URI:        documentResourceURI(doc),
+		URL:        sectionURL(doc, lastHeading(field.Section)),
+		Section:    field.Section,
+		Lines:      fmt.Sprintf("%d-%d", field.StartLine, field.EndLine),
+	}
+}
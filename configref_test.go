@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// loadTestDocument reads a fixture page as extraction would index it.
+func loadTestDocument(t *testing.T, file, pagePath, title string) *Document {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Document{
+		ID:      "v1.11/" + pagePath,
+		Title:   title,
+		Content: string(content),
+		Path:    pagePath,
+		Version: "v1.11",
+	}
+}
+
+func TestConfigKindOfFrontmatterOnlyPage(t *testing.T) {
+	// Generated pages have no H1, so extraction titles them by file name
+	doc := loadTestDocument(t, "testdata/networkruleconfig.mdx",
+		"talos/v1.11/reference/configuration/network/networkruleconfig", "networkruleconfig")
+
+	if kind := configDocumentKind(doc); kind != "NetworkRuleConfig" {
+		t.Fatalf("configDocumentKind() = %q, want NetworkRuleConfig", kind)
+	}
+
+	doc.ConfigFields = parseConfigFields(doc)
+	if len(doc.ConfigFields) == 0 {
+		t.Fatal("no fields parsed")
+	}
+	for _, field := range doc.ConfigFields {
+		if field.Kind != "NetworkRuleConfig" {
+			t.Errorf("field %s has kind %q, want NetworkRuleConfig", field.Path, field.Kind)
+		}
+	}
+
+	schemas := buildConfigSchemas(doc.ConfigFields, map[string]*Document{"v1.11/NetworkRuleConfig": doc})
+	input := `apiVersion: v1alpha1
+kind: NetworkRuleConfig
+name: ingress-apid
+portSelector:
+  ports:
+    - 50000
+  protocol: tcp
+ingress:
+  - subnet: 10.0.0.0/8
+`
+	if findings := validateMachineConfig(input, schemas); len(findings) != 0 {
+		t.Errorf("valid NetworkRuleConfig document got findings %+v", findings)
+	}
+}
+
+func TestParseConfigFields(t *testing.T) {
+	doc := loadTestDocument(t, "testdata/config.mdx", "talos/v1.11/reference/configuration/v1alpha1/config", "config")
+	fields := make(map[string]ConfigField)
+	for _, field := range parseConfigFields(doc) {
+		if _, exists := fields[field.Path]; exists {
+			t.Errorf("field %s parsed twice", field.Path)
+		}
+		fields[field.Path] = field
+	}
+
+	tests := []struct {
+		path       string
+		typ        string
+		section    string
+		def        string
+		values     []string
+		deprecated bool
+		examples   int
+		row        string
+	}{
+		{path: "version", typ: "string", values: []string{"v1alpha1"}, row: "|`version`"},
+		{path: "debug", typ: "bool", values: []string{"true", "yes", "false", "no"}, row: "|`debug`"},
+		{path: "machine", typ: "MachineConfig", examples: 1, row: "|`machine`"},
+		{path: "machine.type", typ: "string", section: "machine", values: []string{"controlplane", "worker"}, row: "|`type`"},
+		{path: "machine.network.interfaces", typ: "[]Device", section: "machine > network", examples: 1, row: "|`interfaces`"},
+		{path: "machine.network.interfaces[].mtu", typ: "int", section: "machine > network > interfaces[]", row: "|`mtu`"},
+		{path: "machine.kubelet.extraArgs", typ: "map[string]string", section: "machine > kubelet", row: "|`extraArgs`"},
+		{path: "machine.install.wipe", typ: "bool", section: "machine > install", def: "true", values: []string{"true", "yes", "false", "no"}, row: "|`wipe`"},
+		{path: "machine.install.bootloader", typ: "bool", section: "machine > install", deprecated: true, row: "|`bootloader`"},
+		{path: "cluster.clusterName", typ: "string", section: "cluster", row: "|`clusterName`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			field, ok := fields[tt.path]
+			if !ok {
+				t.Fatalf("field not parsed")
+			}
+			if field.Kind != "Config" || field.Version != "v1.11" {
+				t.Errorf("kind %q version %q, want Config v1.11", field.Kind, field.Version)
+			}
+			if field.Type != tt.typ || field.Section != tt.section || field.Default != tt.def || field.Deprecated != tt.deprecated {
+				t.Errorf("got type %q section %q default %q deprecated %v, want %q %q %q %v",
+					field.Type, field.Section, field.Default, field.Deprecated, tt.typ, tt.section, tt.def, tt.deprecated)
+			}
+			if strings.Join(field.Values, ",") != strings.Join(tt.values, ",") {
+				t.Errorf("values %v, want %v", field.Values, tt.values)
+			}
+			if len(field.Examples) != tt.examples {
+				t.Errorf("%d examples, want %d: %q", len(field.Examples), tt.examples, field.Examples)
+			}
+			if line := lineOf(t, doc.Content, tt.row); field.StartLine != line || field.EndLine != line {
+				t.Errorf("cites lines %d-%d, want the table row on line %d", field.StartLine, field.EndLine, line)
+			}
+			if field.Description == "" || strings.Contains(field.Description, "<") {
+				t.Errorf("description %q is empty or still has markup", field.Description)
+			}
+		})
+	}
+}
+
+func TestConfigDocumentKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "anchor", content: "## ingress[] {#NetworkRuleConfig.ingress.}\n", want: "NetworkRuleConfig"},
+		{name: "YAML example", content: "```yaml\napiVersion: v1alpha1\nkind: KmsgLogConfig\nname: remote\n```\n", want: "KmsgLogConfig"},
+		{name: "anchor before example", content: "```yaml\nkind: Other\n```\n\n## url {#KmsgLogConfig.url}\n", want: "KmsgLogConfig"},
+		{name: "title fallback", content: "| Field | Type |\n", want: "Title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configDocumentKind(&Document{Title: "Title", Content: tt.content}); got != tt.want {
+				t.Errorf("configDocumentKind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFieldKey(t *testing.T) {
+	tests := map[string]string{
+		"machine.network.interfaces[0].vip": "machine.network.interfaces.vip",
+		"machine.network.interfaces[].vip":  "machine.network.interfaces.vip",
+		" Machine.Kubelet.ExtraArgs. ":      "machine.kubelet.extraargs",
+		"cluster.apiServer.certSANs[12]":    "cluster.apiserver.certsans",
+	}
+	for path, want := range tests {
+		if got := configFieldKey(path); got != want {
+			t.Errorf("configFieldKey(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestConfigFieldAvailability(t *testing.T) {
+	field := func(path string) ConfigField { return ConfigField{Path: path} }
+	versions := newConfigFieldVersions(map[string][]ConfigField{
+		"v1.9":  {field("machine.install.bootloader"), field("machine.network.hostname"), field("machine.network.hostname")},
+		"v1.10": {field("machine.network.hostname"), field("machine.features.kubePrism")},
+		"v1.11": {field("machine.network.hostname"), field("machine.features.kubePrism")},
+	})
+
+	tests := []struct {
+		key  string
+		want ConfigFieldAvailability
+	}{
+		{key: "machine.network.hostname", want: ConfigFieldAvailability{Versions: []string{"v1.9", "v1.10", "v1.11"}, FirstSeen: "v1.9", LastSeen: "v1.11"}},
+		{key: "machine.features.kubeprism", want: ConfigFieldAvailability{Versions: []string{"v1.10", "v1.11"}, FirstSeen: "v1.10", LastSeen: "v1.11", AddedIn: "v1.10"}},
+		{key: "machine.install.bootloader", want: ConfigFieldAvailability{Versions: []string{"v1.9"}, FirstSeen: "v1.9", LastSeen: "v1.9", RemovedIn: "v1.10"}},
+		{key: "machine.nope", want: ConfigFieldAvailability{Versions: []string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := versions.availability(tt.key)
+			if strings.Join(got.Versions, ",") != strings.Join(tt.want.Versions, ",") || got.Versions == nil ||
+				got.FirstSeen != tt.want.FirstSeen || got.LastSeen != tt.want.LastSeen ||
+				got.AddedIn != tt.want.AddedIn || got.RemovedIn != tt.want.RemovedIn {
+				t.Errorf("availability() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+
+	findingInvalidYAML    = "invalid_yaml"
+	findingUnknownKind    = "unknown_kind"
+	findingUnknownKey     = "unknown_key"
+	findingTypeMismatch   = "type_mismatch"
+	findingDeprecated     = "deprecated"
+	findingNotValidated   = "not_validated"
+	findingTooManyAliases = "too_many_aliases"
+
+	// maxConfigAliases caps how many alias references one validation
+	// follows. yaml.v3 doesn't limit alias expansion when decoding into
+	// nodes, so nested anchors could otherwise make a small input walk an
+	// exponential number of nodes.
+	maxConfigAliases = 100
+)
+
+// ConfigFinding is one problem found in a machine config.
+type ConfigFinding struct {
+	Severity      string    `json:"severity"`
+	Issue         string    `json:"issue"`
+	Document      int       `json:"document"`
+	Path          string    `json:"path,omitempty"`
+	Line          int       `json:"line,omitempty"`
+	Message       string    `json:"message"`
+	Suggestion    string    `json:"suggestion,omitempty"`
+	Documentation *Citation `json:"documentation,omitempty"`
+}
+
+// configSchema is the documented fields of one config document kind, keyed
+// by path without array markers.
+type configSchema struct {
+	kind   string
+	page   *Document
+	fields map[string]ConfigField
+	// objects holds the paths that have documented fields under them
+	objects map[string]bool
+}
+
+// buildConfigSchemas groups a version's config fields by document kind.
+func buildConfigSchemas(fields []ConfigField, pages map[string]*Document) map[string]*configSchema {
+	schemas := make(map[string]*configSchema)
+	for _, field := range fields {
+		schema := schemas[field.Kind]
+		if schema == nil {
+			schema = &configSchema{
+				kind:    field.Kind,
+				page:    pages[field.Version+"/"+field.Kind],
+				fields:  make(map[string]ConfigField),
+				objects: make(map[string]bool),
+			}
+			schemas[field.Kind] = schema
+		}
+
+		key := indexPattern.ReplaceAllString(field.Path, "")
+		schema.fields[key] = field
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			schema.objects[key[:i]] = true
+		}
+	}
+	return schemas
+}
+
+// rootSchema picks the schema for a document without a kind: the v1alpha1
+// Config, recognised by its top-level machine field.
+func rootSchema(schemas map[string]*configSchema) *configSchema {
+	for _, schema := range schemas {
+		if _, ok := schema.fields["machine"]; ok {
+			return schema
+		}
+	}
+	return nil
+}
+
+type configValidator struct {
+	schema   *configSchema
+	document int
+	findings []ConfigFinding
+	// aliases counts the alias references followed across all documents
+	aliases *int
+}
+
+// validateMachineConfig checks every document of a multi-document config,
+// or config patch, against the documented schemas. Documents with a kind
+// are checked against that kind's reference; the rest against the v1alpha1
+// Config.
+func validateMachineConfig(input string, schemas map[string]*configSchema) []ConfigFinding {
+	findings := []ConfigFinding{}
+	aliases := 0
+	decoder := yaml.NewDecoder(strings.NewReader(input))
+	for document := 1; ; document++ {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if !errors.Is(err, io.EOF) {
+				findings = append(findings, ConfigFinding{
+					Severity: severityError,
+					Issue:    findingInvalidYAML,
+					Document: document,
+					Message:  err.Error(),
+				})
+			}
+			break
+		}
+		if len(root.Content) == 0 {
+			continue
+		}
+
+		node := root.Content[0]
+		if node.Kind != yaml.MappingNode {
+			findings = append(findings, ConfigFinding{
+				Severity: severityWarning,
+				Issue:    findingNotValidated,
+				Document: document,
+				Line:     node.Line,
+				Message:  "document is not a mapping (JSON patches are not validated)",
+			})
+			continue
+		}
+
+		kind := mappingValue(node, "kind")
+		schema := rootSchema(schemas)
+		if kind != "" {
+			schema = schemas[kind]
+		}
+		if schema == nil {
+			var kinds []string
+			for name := range schemas {
+				kinds = append(kinds, name)
+			}
+			sort.Strings(kinds)
+			findings = append(findings, ConfigFinding{
+				Severity:   severityError,
+				Issue:      findingUnknownKind,
+				Document:   document,
+				Line:       node.Line,
+				Message:    fmt.Sprintf("config document kind %q is not documented", kind),
+				Suggestion: closestName(kind, kinds),
+			})
+			continue
+		}
+
+		validator := &configValidator{schema: schema, document: document, aliases: &aliases}
+		validator.walkMapping(node, "", "", kind != "")
+		findings = append(findings, validator.findings...)
+	}
+	return findings
+}
+
+// mappingValue returns the scalar value of key in a mapping node.
+func mappingValue(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// walkMapping checks the keys of an object. parent is its schema path,
+// without array markers, and location the path reported to the user, with
+// list indices.
+func (v *configValidator) walkMapping(node *yaml.Node, parent, location string, typed bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path, keyLocation := key.Value, key.Value
+		if parent != "" {
+			path = parent + "." + key.Value
+			keyLocation = location + "." + key.Value
+		}
+		// Typed documents identify themselves with apiVersion and kind
+		if parent == "" && typed && (key.Value == "apiVersion" || key.Value == "kind") {
+			continue
+		}
+
+		field, ok := v.schema.fields[path]
+		if !ok {
+			v.findings = append(v.findings, ConfigFinding{
+				Severity:      severityError,
+				Issue:         findingUnknownKey,
+				Document:      v.document,
+				Path:          keyLocation,
+				Line:          key.Line,
+				Message:       fmt.Sprintf("%s is not a documented %s field", keyLocation, v.schema.kind),
+				Suggestion:    v.suggestKey(parent, key.Value),
+				Documentation: v.parentCitation(parent),
+			})
+			continue
+		}
+
+		if field.Deprecated {
+			v.findings = append(v.findings, ConfigFinding{
+				Severity:      severityWarning,
+				Issue:         findingDeprecated,
+				Document:      v.document,
+				Path:          keyLocation,
+				Line:          key.Line,
+				Message:       field.Description,
+				Documentation: v.citation(field),
+			})
+		}
+		v.checkValue(value, path, keyLocation, field.Type, field)
+	}
+}
+
+// checkValue checks a value against its documented type: lists, maps,
+// objects with documented fields, and the scalar kinds YAML can tell
+// apart. Other named types, e.g. durations, are accepted as is.
+func (v *configValidator) checkValue(node *yaml.Node, path, location, typ string, field ConfigField) {
+	if node.Kind == yaml.AliasNode {
+		if *v.aliases++; *v.aliases > maxConfigAliases {
+			if *v.aliases == maxConfigAliases+1 {
+				v.findings = append(v.findings, ConfigFinding{
+					Severity: severityError,
+					Issue:    findingTooManyAliases,
+					Document: v.document,
+					Path:     location,
+					Line:     node.Line,
+					Message:  fmt.Sprintf("the config follows more than %d aliases; values behind further aliases are not validated", maxConfigAliases),
+				})
+			}
+			return
+		}
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	typ = strings.TrimPrefix(typ, "*")
+
+	expected := ""
+	switch {
+	case strings.HasPrefix(typ, "[]") && typ != "[]byte":
+		if node.Kind != yaml.SequenceNode {
+			expected = "a list"
+			break
+		}
+		for i, item := range node.Content {
+			v.checkValue(item, path, fmt.Sprintf("%s[%d]", location, i), typ[2:], field)
+		}
+	case strings.HasPrefix(typ, "map["):
+		if node.Kind != yaml.MappingNode {
+			expected = "a map"
+			break
+		}
+		_, valueType, _ := strings.Cut(typ, "]")
+		for i := 1; i < len(node.Content); i += 2 {
+			key := node.Content[i-1].Value
+			v.checkValue(node.Content[i], path+"."+key, location+"."+key, valueType, field)
+		}
+	case v.schema.objects[path]:
+		if node.Kind != yaml.MappingNode {
+			expected = "an object"
+			break
+		}
+		v.walkMapping(node, path, location, false)
+	case typ == "bool":
+		if node.Tag != "!!bool" {
+			expected = "a boolean"
+		}
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		if node.Tag != "!!int" {
+			expected = "an integer"
+		}
+	case strings.HasPrefix(typ, "float"):
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			expected = "a number"
+		}
+	case typ == "string" || typ == "[]byte":
+		if node.Kind != yaml.ScalarNode {
+			expected = "a string"
+		}
+	}
+
+	// yaml.v3 still decodes yes and no into bools, as the docs list them
+	if expected != "" && node.Kind == yaml.ScalarNode && slices.Contains(field.Values, node.Value) {
+		expected = ""
+	}
+	if expected != "" {
+		v.findings = append(v.findings, ConfigFinding{
+			Severity:      severityError,
+			Issue:         findingTypeMismatch,
+			Document:      v.document,
+			Path:          location,
+			Line:          node.Line,
+			Message:       fmt.Sprintf("%s should be %s (%s), got %s", location, expected, typ, describeNode(node)),
+			Documentation: v.citation(field),
+		})
+	}
+}
+
+// describeNode names the kind of value a node holds.
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a map"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!bool":
+		return "a boolean"
+	case "!!int":
+		return "an integer"
+	case "!!float":
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+// suggestKey picks the documented sibling of an unknown key it most
+// likely misspells.
+func (v *configValidator) suggestKey(parent, name string) string {
+	prefix := ""
+	if parent != "" {
+		prefix = parent + "."
+	}
+	var siblings []string
+	for key := range v.schema.fields {
+		if rest, ok := strings.CutPrefix(key, prefix); ok && !strings.Contains(rest, ".") {
+			siblings = append(siblings, rest)
+		}
+	}
+	sort.Strings(siblings)
+	if suggestion := closestName(name, siblings); suggestion != "" {
+		return prefix + suggestion
+	}
+	return ""
+}
+
+// parentCitation cites where the fields under parent are documented: the
+// parent field's own row, or the page for top-level fields.
+func (v *configValidator) parentCitation(parent string) *Citation {
+	if field, ok := v.schema.fields[parent]; ok {
+		return v.citation(field)
+	}
+	if v.schema.page == nil {
+		return nil
+	}
+	citation := documentCitation(v.schema.page, nil)
+	return &citation
+}
+
+func (v *configValidator) citation(field ConfigField) *Citation {
+	if v.schema.page == nil {
+		return nil
+	}
+	citation := configFieldCitation(v.schema.page, field)
+	return &citation
+}
+
+// closestName returns the candidate matching name case-insensitively, or
+// else the one within two edits of it, if any.
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, name) {
+			return candidate
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func (s *TalosDocMCPServer) handleValidateMachineConfig(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("config")
+	if err != nil {
+		return mcp.NewToolResultError("config is required"), nil
+	}
+	version, err := s.resolveVersion(request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	byVersion, pages := s.configFields()
+	if len(byVersion[version]) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no machine configuration reference is indexed for %s", version)), nil
+	}
+
+	findings := validateMachineConfig(config, buildConfigSchemas(byVersion[version], pages))
+	counts := map[string]int{severityError: 0, severityWarning: 0}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+
+	result := map[string]interface{}{
+		"version":  version,
+		"valid":    counts[severityError] == 0,
+		"errors":   counts[severityError],
+		"warnings": counts[severityWarning],
+		"findings": findings,
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
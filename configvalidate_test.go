@@ -0,0 +1,196 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testConfigSchemas builds the schemas of the fixture reference pages: the
+// v1alpha1 Config and the typed NetworkRuleConfig document.
+func testConfigSchemas(t *testing.T) map[string]*configSchema {
+	t.Helper()
+	pages := make(map[string]*Document)
+	var fields []ConfigField
+	for _, fixture := range []struct{ file, path, title string }{
+		{"testdata/config.mdx", "talos/v1.11/reference/configuration/v1alpha1/config", "config"},
+		{"testdata/networkruleconfig.mdx", "talos/v1.11/reference/configuration/network/networkruleconfig", "networkruleconfig"},
+	} {
+		doc := loadTestDocument(t, fixture.file, fixture.path, fixture.title)
+		doc.ConfigFields = parseConfigFields(doc)
+		pages[doc.Version+"/"+doc.ConfigFields[0].Kind] = doc
+		fields = append(fields, doc.ConfigFields...)
+	}
+	return buildConfigSchemas(fields, pages)
+}
+
+func TestValidateMachineConfig(t *testing.T) {
+	schemas := testConfigSchemas(t)
+
+	type finding struct {
+		issue    string
+		document int
+		path     string
+	}
+	tests := []struct {
+		name           string
+		config         string
+		want           []finding
+		wantSuggestion string
+	}{
+		{
+			name: "valid",
+			config: `version: v1alpha1
+machine:
+  type: controlplane
+  network:
+    hostname: cp-1
+    interfaces:
+      - interface: eth0
+        addresses: [10.0.0.2/24]
+        mtu: 1500
+        dhcp: false
+  kubelet:
+    extraArgs:
+      rotate-server-certificates: "true"
+  install:
+    wipe: yes
+cluster:
+  clusterName: demo
+`,
+		},
+		{
+			name:           "unknown top-level key",
+			config:         "machin:\n  type: worker\n",
+			want:           []finding{{findingUnknownKey, 1, "machin"}},
+			wantSuggestion: "machine",
+		},
+		{
+			name:           "unknown nested key with list index",
+			config:         "machine:\n  network:\n    interfaces:\n      - interface: eth0\n        mtuu: 1500\n",
+			want:           []finding{{findingUnknownKey, 1, "machine.network.interfaces[0].mtuu"}},
+			wantSuggestion: "machine.network.interfaces.mtu",
+		},
+		{
+			name:   "string where an integer is expected",
+			config: "machine:\n  network:\n    interfaces:\n      - interface: eth0\n        mtu: \"1500\"\n",
+			want:   []finding{{findingTypeMismatch, 1, "machine.network.interfaces[0].mtu"}},
+		},
+		{
+			name:   "scalar where a list is expected",
+			config: "machine:\n  network:\n    interfaces:\n      - addresses: 10.0.0.2/24\n",
+			want:   []finding{{findingTypeMismatch, 1, "machine.network.interfaces[0].addresses"}},
+		},
+		{
+			name:   "list where a map is expected",
+			config: "machine:\n  kubelet:\n    extraArgs: [a, b]\n",
+			want:   []finding{{findingTypeMismatch, 1, "machine.kubelet.extraArgs"}},
+		},
+		{
+			name:   "scalar where an object is expected",
+			config: "machine:\n  network: dhcp\n",
+			want:   []finding{{findingTypeMismatch, 1, "machine.network"}},
+		},
+		{
+			name:   "deprecated field",
+			config: "machine:\n  install:\n    bootloader: true\n",
+			want:   []finding{{findingDeprecated, 1, "machine.install.bootloader"}},
+		},
+		{
+			name:   "nulls are accepted for any type",
+			config: "machine:\n  network:\n    hostname: null\n    interfaces: ~\n  kubelet:\n    extraArgs:\n",
+		},
+		{
+			name: "typed multi-document config",
+			config: `machine:
+  type: worker
+---
+apiVersion: v1alpha1
+kind: NetworkRuleConfig
+name: ingress-apid
+portSelector:
+  ports: [50000]
+  protocl: tcp
+---
+apiVersion: v1alpha1
+kind: NetworkRuleConfg
+name: typo
+`,
+			want: []finding{
+				{findingUnknownKey, 2, "portSelector.protocl"},
+				{findingUnknownKind, 3, ""},
+			},
+		},
+		{
+			name:   "JSON patch",
+			config: "- op: add\n  path: /machine/network/hostname\n  value: cp-1\n",
+			want:   []finding{{findingNotValidated, 1, ""}},
+		},
+		{
+			name:   "invalid YAML",
+			config: "machine:\n  type: [worker\n",
+			want:   []finding{{findingInvalidYAML, 1, ""}},
+		},
+		{
+			name: "aliases are checked like the values they point at",
+			config: `machine:
+  network:
+    interfaces:
+      - &eth0
+        interface: eth0
+        mtu: 1500
+      - *eth0
+  kubelet:
+    clusterDNS: &dns [10.96.0.10]
+    extraArgs: *dns
+`,
+			want: []finding{{findingTypeMismatch, 1, "machine.kubelet.extraArgs"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := validateMachineConfig(tt.config, schemas)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %d findings %+v, want %d", len(findings), findings, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := findings[i]
+				if got.Issue != want.issue || got.Document != want.document || (want.path != "" && got.Path != want.path) {
+					t.Errorf("finding %d = %s in document %d at %q, want %s in document %d at %q",
+						i, got.Issue, got.Document, got.Path, want.issue, want.document, want.path)
+				}
+			}
+			if tt.wantSuggestion != "" && findings[0].Suggestion != tt.wantSuggestion {
+				t.Errorf("suggestion = %q, want %q", findings[0].Suggestion, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
+func TestValidateMachineConfigAliasLimit(t *testing.T) {
+	schemas := testConfigSchemas(t)
+
+	// Aliases multiply: twelve references to a device that itself refers
+	// to an address twelve times walk 156 aliases from a few lines
+	config := `machine:
+  install:
+    disk: &ip 10.0.0.2/24
+  network:
+    hostname: &device {interface: eth0, addresses: [` + strings.TrimSuffix(strings.Repeat("*ip, ", 12), ", ") + `]}
+    interfaces: [` + strings.TrimSuffix(strings.Repeat("*device, ", 12), ", ") + `]
+`
+
+	findings := validateMachineConfig(config, schemas)
+	limited := 0
+	for _, finding := range findings {
+		if finding.Issue == findingTooManyAliases {
+			limited++
+		}
+	}
+	if limited != 1 {
+		t.Errorf("got %d %s findings, want 1: %+v", limited, findingTooManyAliases, findings)
+	}
+	if len(findings) > maxConfigAliases+1 {
+		t.Errorf("validation produced %d findings, it kept walking aliases past the limit", len(findings))
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtractionReport records everything ExtractDocuments skipped or couldn't
+// make sense of, so upstream restructures show up instead of pages quietly
+// disappearing from the index.
+type ExtractionReport struct {
+	GeneratedAt        time.Time   `json:"generated_at"`
+	Documents          int         `json:"documents"`
+	MissingFiles       []PageIssue `json:"missing_files"`
+	EmptyFiles         []PageIssue `json:"empty_files"`
+	DuplicateIDs       []PageIssue `json:"duplicate_ids"`
+	InvalidFrontmatter []PageIssue `json:"invalid_frontmatter"`
+	OrphanFiles        []string    `json:"orphan_files"`
+	NavigationWarnings []string    `json:"navigation_warnings"`
+	PublicURLIssues    []PageIssue `json:"public_url_issues"`
+	BrokenLinks        []PageIssue `json:"broken_links"`
+}
+
+// PageIssue is a problem with one page referenced from docs.json.
+type PageIssue struct {
+	ID       string `json:"id"`
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Location string `json:"location"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+func newExtractionReport() *ExtractionReport {
+	// Empty slices rather than nil so the JSON report always lists every
+	// category, even when it's clean
+	return &ExtractionReport{
+		MissingFiles:       []PageIssue{},
+		EmptyFiles:         []PageIssue{},
+		DuplicateIDs:       []PageIssue{},
+		InvalidFrontmatter: []PageIssue{},
+		OrphanFiles:        []string{},
+		NavigationWarnings: []string{},
+		PublicURLIssues:    []PageIssue{},
+		BrokenLinks:        []PageIssue{},
+	}
+}
+
+// IssueCount is the total number of problems in the report.
+func (r *ExtractionReport) IssueCount() int {
+	return len(r.MissingFiles) + len(r.EmptyFiles) + len(r.DuplicateIDs) +
+		len(r.InvalidFrontmatter) + len(r.OrphanFiles) + len(r.NavigationWarnings) +
+		len(r.PublicURLIssues) + len(r.BrokenLinks)
+}
+
+// Summary counts issues per category.
+func (r *ExtractionReport) Summary() map[string]int {
+	return map[string]int{
+		"missing_files":       len(r.MissingFiles),
+		"empty_files":         len(r.EmptyFiles),
+		"duplicate_ids":       len(r.DuplicateIDs),
+		"invalid_frontmatter": len(r.InvalidFrontmatter),
+		"orphan_files":        len(r.OrphanFiles),
+		"navigation_warnings": len(r.NavigationWarnings),
+		"public_url_issues":   len(r.PublicURLIssues),
+		"broken_links":        len(r.BrokenLinks),
+	}
+}
+
+// parseFrontmatter splits a leading YAML frontmatter block from content. A
+// page without frontmatter returns nil fields and no error.
+func parseFrontmatter(content string) (map[string]interface{}, error) {
+	trimmed := strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(trimmed, "---\n") && !strings.HasPrefix(trimmed, "---\r\n") {
+		return nil, nil
+	}
+
+	rest := trimmed[strings.Index(trimmed, "\n")+1:]
+	if strings.HasPrefix(rest, "---") {
+		return map[string]interface{}{}, nil
+	}
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return nil, fmt.Errorf("frontmatter block is not closed")
+	}
+
+	fields := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(rest[:end]), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// findOrphanFiles lists markdown files under the directories navigation
+// points into that no navigation entry references. referenced holds
+// normalized page paths.
+func (df *DocumentationFetcher) findOrphanFiles(referenced map[string]bool) []string {
+	publicDir := filepath.Join(df.localPath, "public")
+
+	// Only scan the top two levels pages live under (e.g. talos/v1.11), so
+	// other products sharing the repo aren't reported
+	roots := make(map[string]bool)
+	for pagePath := range referenced {
+		parts := strings.SplitN(pagePath, "/", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		roots[parts[0]+"/"+parts[1]] = true
+	}
+
+	var orphans []string
+	for root := range roots {
+		filepath.Walk(filepath.Join(publicDir, filepath.FromSlash(root)), func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := info.Name()
+			if info.IsDir() {
+				// Snippets are included by other pages, never navigated to
+				if strings.HasPrefix(name, "_") || name == "snippets" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(name); ext != ".mdx" && ext != ".md" {
+				return nil
+			}
+
+			rel, err := filepath.Rel(publicDir, filePath)
+			if err != nil {
+				return nil
+			}
+			if pagePath := normalizePagePath(filepath.ToSlash(rel)); !referenced[pagePath] {
+				orphans = append(orphans, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	}
+
+	sort.Strings(orphans)
+	return orphans
+}
+
+func isBlank(content []byte) bool {
+	return len(bytes.TrimSpace(content)) == 0
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// documentChunkSize is how much page content a response carries by
+	// default; the rest is fetched with the continuation cursor
+	documentChunkSize = 10000
+
+	// maxDocumentChunkSize caps max_bytes
+	maxDocumentChunkSize = 100000
+)
+
+// documentCursor is the state behind a continuation token: which page, the
+// content it was issued for, and the part of the selection still to send.
+type documentCursor struct {
+	ID      string `json:"id"`
+	Hash    string `json:"hash"`
+	Offset  int    `json:"offset"`
+	End     int    `json:"end"`
+	Section string `json:"section,omitempty"`
+}
+
+func encodeCursor(cursor documentCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (documentCursor, error) {
+	var cursor documentCursor
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+func documentHash(doc *Document) string {
+	if doc.ContentHash != "" {
+		return doc.ContentHash
+	}
+	return contentHash(doc.Content)
+}
+
+// chunkEnd returns where a chunk of at most budget bytes starting at start
+// should end: before end, on a rune boundary and, when one falls in the
+// second half of the chunk, just after a newline. A chunk always holds at
+// least one rune, even when that is more than budget, so following cursors
+// reaches the end.
+func chunkEnd(content string, start, end, budget int) int {
+	if end-start <= budget {
+		return end
+	}
+	cut := start + budget
+	for cut > start && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	if cut == start {
+		_, size := utf8.DecodeRuneInString(content[start:end])
+		return start + size
+	}
+	if newline := strings.LastIndexByte(content[start:cut], '\n'); newline >= budget/2 {
+		return start + newline + 1
+	}
+	return cut
+}
+
+// documentPreview returns a copy of doc holding the first chunk of its
+// content, and the cursor get_talos_document continues from if the page is
+// longer. Parsed structured data is left out; it has its own tools.
+func documentPreview(doc *Document) (*Document, string) {
+	preview := *doc
+	preview.ReleaseNotes = nil
+	preview.ConfigFields = nil
+	preview.TalosctlCommands = nil
+	preview.Links = nil
+
+	end := chunkEnd(doc.Content, 0, len(doc.Content), documentChunkSize)
+	if end == len(doc.Content) {
+		return &preview, ""
+	}
+	preview.Content = doc.Content[:end]
+	return &preview, encodeCursor(documentCursor{
+		ID:     doc.ID,
+		Hash:   documentHash(doc),
+		Offset: end,
+		End:    len(doc.Content),
+	})
+}
+
+// resolveDocumentRef finds a page by document ID, page path, talos-docs://
+// URI or public docs URL. A URL's fragment is returned as the section
+// anchor it points at.
+func (s *TalosDocMCPServer) resolveDocumentRef(ref, version string) (*Document, string, bool) {
+	ref = strings.TrimSpace(ref)
+	anchor := ""
+	if strings.HasPrefix(ref, resourceScheme) {
+		ref = strings.TrimPrefix(ref, resourceScheme)
+	} else if parsed, err := url.Parse(ref); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		ref = parsed.Path
+		anchor = parsed.Fragment
+	}
+
+	doc, exists := s.searchEngine.LookupDocument(ref, version)
+	return doc, anchor, exists
+}
+
+// headingAnchor is the anchor docs sites derive from a heading, e.g.
+// "Layer 2 VIP" becomes layer-2-vip.
+func headingAnchor(heading string) string {
+	heading = headingAnchorPattern.ReplaceAllString(heading, "")
+	var anchor strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > utf8.RuneSelf:
+			if dash && anchor.Len() > 0 {
+				anchor.WriteByte('-')
+			}
+			anchor.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return anchor.String()
+}
+
+// findSection picks the section whose heading, trail or anchor matches
+// name, and returns its line range including its subsections.
+func findSection(content, name string) (markdownSection, int, bool) {
+	name = strings.TrimSpace(strings.TrimPrefix(name, "#"))
+	sections := splitSections(content)
+	for i, section := range sections {
+		if section.Level == 0 {
+			continue
+		}
+		// Trails may leave out the page title heading
+		trail := strings.ToLower(section.Trail)
+		if !strings.EqualFold(section.Heading, name) && trail != strings.ToLower(name) &&
+			!strings.HasSuffix(trail, " > "+strings.ToLower(name)) && headingAnchor(section.Heading) != strings.ToLower(name) {
+			continue
+		}
+
+		endLine := section.EndLine
+		for _, next := range sections[i+1:] {
+			if next.Level <= section.Level {
+				break
+			}
+			endLine = next.EndLine
+		}
+		return section, endLine, true
+	}
+	return markdownSection{}, 0, false
+}
+
+// lineOffsets returns the byte offset at which each line starts, plus the
+// content length, so line n spans offsets[n-1]:offsets[n].
+func lineOffsets(content string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' && i+1 < len(content) {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return append(offsets, len(content))
+}
+
+// lineAt returns the 1-based line holding byte offset.
+func lineAt(offsets []int, offset int) int {
+	line := 1
+	for line < len(offsets)-1 && offsets[line] <= offset {
+		line++
+	}
+	return line
+}
+
+func (s *TalosDocMCPServer) handleGetDocument(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	budget := request.GetInt("max_bytes", documentChunkSize)
+	if budget <= 0 || budget > maxDocumentChunkSize {
+		return mcp.NewToolResultError(fmt.Sprintf("max_bytes must be between 1 and %d", maxDocumentChunkSize)), nil
+	}
+
+	var doc *Document
+	var cursor documentCursor
+	if token := request.GetString("cursor", ""); token != "" {
+		var err error
+		if cursor, err = decodeCursor(token); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var exists bool
+		if doc, exists = s.searchEngine.GetDocument(cursor.ID); !exists {
+			return mcp.NewToolResultError(fmt.Sprintf("document %s is no longer indexed", cursor.ID)), nil
+		}
+		if documentHash(doc) != cursor.Hash || cursor.Offset < 0 || cursor.Offset > cursor.End || cursor.End > len(doc.Content) {
+			return mcp.NewToolResultError(fmt.Sprintf("document %s changed since the cursor was issued; request it again", cursor.ID)), nil
+		}
+	} else {
+		ref := request.GetString("ref", "")
+		if ref == "" {
+			return mcp.NewToolResultError("ref or cursor is required"), nil
+		}
+		found, anchor, exists := s.resolveDocumentRef(ref, request.GetString("version", ""))
+		if !exists {
+			return mcp.NewToolResultError(fmt.Sprintf("no document %s; find IDs with search_talos_docs or browse_talos_docs", ref)), nil
+		}
+
+		var err error
+		if cursor, err = selectDocumentRange(found, request, anchor); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		doc = found
+	}
+
+	offsets := lineOffsets(doc.Content)
+	end := chunkEnd(doc.Content, cursor.Offset, cursor.End, budget)
+	result := map[string]interface{}{
+		"id":          doc.ID,
+		"title":       doc.Title,
+		"version":     doc.Version,
+		"path":        doc.Path,
+		"uri":         documentResourceURI(doc),
+		"url":         doc.URL,
+		"total_bytes": len(doc.Content),
+		"total_lines": len(offsets) - 1,
+		"range": map[string]int{
+			"start_byte": cursor.Offset,
+			"end_byte":   end,
+			"start_line": lineAt(offsets, cursor.Offset),
+			"end_line":   lineAt(offsets, max(cursor.Offset, end-1)),
+		},
+		"content": doc.Content[cursor.Offset:end],
+	}
+	if cursor.Section != "" {
+		result["section"] = cursor.Section
+		result["url"] = sectionURL(doc, lastHeading(cursor.Section))
+	}
+	if end < cursor.End {
+		next := cursor
+		next.Offset = end
+		result["next_cursor"] = encodeCursor(next)
+		result["remaining_bytes"] = cursor.End - end
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// selectDocumentRange turns the requested section, line range or byte
+// range (at most one of them) into the cursor for its first chunk. A URL
+// anchor selects its section when nothing else is requested.
+func selectDocumentRange(doc *Document, request mcp.CallToolRequest, anchor string) (documentCursor, error) {
+	cursor := documentCursor{ID: doc.ID, Hash: documentHash(doc), End: len(doc.Content)}
+	offsets := lineOffsets(doc.Content)
+	totalLines := len(offsets) - 1
+
+	section := request.GetString("section", "")
+	startLine, endLine := request.GetInt("start_line", 0), request.GetInt("end_line", 0)
+	startByte, endByte := request.GetInt("start_byte", -1), request.GetInt("end_byte", -1)
+	byLines := startLine != 0 || endLine != 0
+	byBytes := startByte != -1 || endByte != -1
+
+	selections := 0
+	for _, selected := range []bool{section != "", byLines, byBytes} {
+		if selected {
+			selections++
+		}
+	}
+	if selections > 1 {
+		return cursor, fmt.Errorf("select a section, a line range or a byte range, not several")
+	}
+	if selections == 0 && anchor != "" {
+		section = anchor
+	}
+
+	switch {
+	case section != "":
+		found, last, ok := findSection(doc.Content, section)
+		if !ok {
+			return cursor, fmt.Errorf("no section %q in %s", section, doc.ID)
+		}
+		cursor.Offset, cursor.End = offsets[found.StartLine-1], offsets[last]
+		cursor.Section = found.Trail
+	case byLines:
+		if startLine == 0 {
+			startLine = 1
+		}
+		if endLine == 0 || endLine > totalLines {
+			endLine = totalLines
+		}
+		if startLine < 1 || startLine > endLine {
+			return cursor, fmt.Errorf("invalid line range %d-%d (the page has %d lines)", startLine, endLine, totalLines)
+		}
+		cursor.Offset, cursor.End = offsets[startLine-1], offsets[endLine]
+	case byBytes:
+		if startByte == -1 {
+			startByte = 0
+		}
+		if endByte == -1 || endByte > len(doc.Content) {
+			endByte = len(doc.Content)
+		}
+		if startByte < 0 || startByte > endByte {
+			return cursor, fmt.Errorf("invalid byte range %d-%d (the page has %d bytes)", startByte, endByte, len(doc.Content))
+		}
+		// Keep the range on rune boundaries
+		for startByte < endByte && !utf8.RuneStart(doc.Content[startByte]) {
+			startByte++
+		}
+		for endByte < len(doc.Content) && !utf8.RuneStart(doc.Content[endByte]) {
+			endByte++
+		}
+		cursor.Offset, cursor.End = startByte, endByte
+	}
+	return cursor, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const testPage = `# Virtual IP
+
+Talos can share a virtual IP between control plane nodes.
+
+## Layer 2 VIP
+
+Nodes elect a leader — the VIP moves with it: 日本語 ünïcödé.
+
+### Choosing an IP
+
+Pick an unused address.
+
+## Caveats
+
+The VIP needs etcd.
+`
+
+func testPageDocument() *Document {
+	return &Document{ID: "v1.11/talos/v1.11/networking/vip", Content: testPage, Version: "v1.11"}
+}
+
+func TestChunkEnd(t *testing.T) {
+	content := "ab\ncd\n日本語"
+	tests := []struct {
+		name               string
+		start, end, budget int
+		want               int
+	}{
+		{name: "fits", start: 0, end: len(content), budget: 100, want: len(content)},
+		{name: "cut after newline in second half", start: 0, end: len(content), budget: 7, want: 6},
+		{name: "no newline in second half", start: 0, end: len(content), budget: 2, want: 2},
+		{name: "backs off to a rune boundary", start: 6, end: len(content), budget: 4, want: 9},
+		{name: "budget below one rune still advances", start: 6, end: len(content), budget: 1, want: 9},
+		{name: "budget below one rune mid-page", start: 9, end: len(content), budget: 2, want: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkEnd(content, tt.start, tt.end, tt.budget); got != tt.want {
+				t.Errorf("chunkEnd(%d, %d, %d) = %d, want %d", tt.start, tt.end, tt.budget, got, tt.want)
+			}
+		})
+	}
+}
+
+// Following cursors must always reach the end of the page, whatever the
+// budget, and never split a rune.
+func TestChunksCoverMultiByteContent(t *testing.T) {
+	for budget := 1; budget <= 8; budget++ {
+		var chunks []string
+		for offset := 0; offset < len(testPage); {
+			end := chunkEnd(testPage, offset, len(testPage), budget)
+			if end <= offset {
+				t.Fatalf("budget %d: chunk at %d is empty, a client following cursors would loop", budget, offset)
+			}
+			chunk := testPage[offset:end]
+			if !utf8.ValidString(chunk) {
+				t.Fatalf("budget %d: chunk %q splits a rune", budget, chunk)
+			}
+			chunks = append(chunks, chunk)
+			offset = end
+		}
+		if got := strings.Join(chunks, ""); got != testPage {
+			t.Fatalf("budget %d: chunks reassemble to %q", budget, got)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := documentCursor{ID: "v1.11/talos/v1.11/networking/vip", Hash: "abc", Offset: 10, End: 42, Section: "Virtual IP > Layer 2 VIP"}
+	decoded, err := decodeCursor(" " + encodeCursor(cursor) + "\n")
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if decoded != cursor {
+		t.Errorf("decodeCursor() = %+v, want %+v", decoded, cursor)
+	}
+
+	for _, token := range []string{"", "not base64!", encodeCursor(documentCursor{Offset: 3}), "bnVsbA"} {
+		if _, err := decodeCursor(token); err == nil {
+			t.Errorf("decodeCursor(%q) accepted an invalid cursor", token)
+		}
+	}
+}
+
+func TestSelectDocumentRange(t *testing.T) {
+	doc := testPageDocument()
+	offsets := lineOffsets(doc.Content)
+	layer2 := strings.Index(testPage, "## Layer 2 VIP")
+	caveats := strings.Index(testPage, "## Caveats")
+	multiByte := strings.Index(testPage, "日")
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		anchor    string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{name: "whole page", wantStart: 0, wantEnd: len(testPage)},
+		{name: "section by heading", args: map[string]any{"section": "Layer 2 VIP"}, wantStart: layer2, wantEnd: caveats},
+		{name: "section by anchor", args: map[string]any{"section": "#layer-2-vip"}, wantStart: layer2, wantEnd: caveats},
+		{name: "section by trail", args: map[string]any{"section": "Virtual IP > Caveats"}, wantStart: caveats, wantEnd: len(testPage)},
+		{name: "URL anchor", anchor: "caveats", wantStart: caveats, wantEnd: len(testPage)},
+		{name: "explicit selection wins over URL anchor", args: map[string]any{"start_line": 1, "end_line": 1}, anchor: "caveats", wantStart: 0, wantEnd: offsets[1]},
+		{name: "line range", args: map[string]any{"start_line": float64(3), "end_line": float64(5)}, wantStart: offsets[2], wantEnd: offsets[5]},
+		{name: "open-ended line range", args: map[string]any{"start_line": 13}, wantStart: offsets[12], wantEnd: len(testPage)},
+		{name: "byte range on rune boundaries", args: map[string]any{"start_byte": multiByte + 1, "end_byte": multiByte + 4}, wantStart: multiByte + 3, wantEnd: multiByte + 6},
+		{name: "unknown section", args: map[string]any{"section": "Nope"}, wantErr: true},
+		{name: "inverted line range", args: map[string]any{"start_line": 5, "end_line": 2}, wantErr: true},
+		{name: "several selections", args: map[string]any{"section": "Caveats", "start_line": 1}, wantErr: true},
+		{name: "negative byte range", args: map[string]any{"start_byte": -5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tt.args
+			cursor, err := selectDocumentRange(doc, request, tt.anchor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectDocumentRange() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cursor.Offset != tt.wantStart || cursor.End != tt.wantEnd {
+				t.Errorf("selected %d-%d (%q), want %d-%d (%q)", cursor.Offset, cursor.End, testPage[cursor.Offset:cursor.End],
+					tt.wantStart, tt.wantEnd, testPage[tt.wantStart:tt.wantEnd])
+			}
+			if cursor.ID != doc.ID || cursor.Hash != documentHash(doc) {
+				t.Errorf("cursor %+v doesn't identify the page", cursor)
+			}
+		})
+	}
+}
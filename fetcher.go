@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -18,6 +20,8 @@ type DocumentationFetcher struct {
 	repoURL      string
 	localPath    string
 	branch       string
+	historyDepth int
+	publicURL    string
 	gitRepo      *git.Repository
 	lastSync     time.Time
 	syncMode     SyncMode
@@ -26,11 +30,22 @@ type DocumentationFetcher struct {
 	stopChan     chan struct{}
 }
 
-func NewDocumentationFetcher(repoURL, localPath, branch string) (*DocumentationFetcher, error) {
+// defaultRepoPath is where the docs repository is checked out.
+func defaultRepoPath() string {
+	return filepath.Join(os.TempDir(), "talos-docs-repo")
+}
+
+func NewDocumentationFetcher(repoURL, localPath, branch string, historyDepth int, publicURL string) (*DocumentationFetcher, error) {
+	if historyDepth < 1 {
+		historyDepth = 1
+	}
+
 	df := &DocumentationFetcher{
-		repoURL:     repoURL,
-		localPath:   localPath,
-		branch:      branch,
+		repoURL:      repoURL,
+		localPath:    localPath,
+		branch:       branch,
+		historyDepth: historyDepth,
+		publicURL:    publicURL,
 		syncMode:    Hybrid,
 		webhookChan: make(chan WebhookEvent, 100),
 		stopChan:    make(chan struct{}),
@@ -57,7 +72,7 @@ func (df *DocumentationFetcher) initRepository() error {
 			URL:           df.repoURL,
 			SingleBranch:  true,
 			ReferenceName: plumbing.NewBranchReferenceName(df.branch),
-			Depth:         1,
+			Depth:         df.historyDepth, // Enough history for per-page commit metadata
 			Tags:          git.NoTags,
 			Progress:      os.Stderr, // Show clone progress
 		}
@@ -80,15 +95,21 @@ func (df *DocumentationFetcher) initRepository() error {
 }
 
 func (df *DocumentationFetcher) pullLatest() error {
-	wt, err := df.gitRepo.Worktree()
+	// Existing checkouts aren't opened at startup, so open on demand
+	repo, err := df.openRepository()
+	if err != nil {
+		return err
+	}
+
+	wt, err := repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
 
 	// Fetch latest changes
-	if err := df.gitRepo.Fetch(&git.FetchOptions{
+	if err := repo.Fetch(&git.FetchOptions{
 		RemoteURL: df.repoURL,
-		Depth:     1,
+		Depth:     df.historyDepth,
 		Tags:      git.NoTags,
 	}); err != nil && err != git.NoErrAlreadyUpToDate {
 		return fmt.Errorf("failed to fetch: %w", err)
@@ -147,21 +168,26 @@ func (df *DocumentationFetcher) pollForUpdates() error {
 		return nil
 	}
 
+	repo, err := df.openRepository()
+	if err != nil {
+		return err
+	}
+
 	// Check for new commits
-	remote, err := df.gitRepo.Remote("origin")
+	remote, err := repo.Remote("origin")
 	if err != nil {
 		return fmt.Errorf("failed to get remote: %w", err)
 	}
 
 	if err := remote.Fetch(&git.FetchOptions{
-		Depth: 1,
+		Depth: df.historyDepth,
 		Tags:  git.NoTags,
 	}); err != nil && err != git.NoErrAlreadyUpToDate {
 		return fmt.Errorf("failed to fetch during poll: %w", err)
 	}
 
 	// Check if HEAD changed
-	ref, err := df.gitRepo.Head()
+	ref, err := repo.Head()
 	if err != nil {
 		return fmt.Errorf("failed to get HEAD: %w", err)
 	}
@@ -190,6 +216,20 @@ func (df *DocumentationFetcher) syncWithCommit(commitSHA string) error {
 	return nil
 }
 
+// HeadCommit returns the commit SHA the local checkout is at.
+func (df *DocumentationFetcher) HeadCommit() (string, error) {
+	repo, err := df.openRepository()
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return ref.Hash().String(), nil
+}
+
 func (df *DocumentationFetcher) GetNavigation() (*DocsNavigation, error) {
 	docsPath := filepath.Join(df.localPath, "public", "docs.json")
 	
@@ -204,18 +244,52 @@ func (df *DocumentationFetcher) GetNavigation() (*DocsNavigation, error) {
 		return nil, fmt.Errorf("failed to decode docs.json: %w", err)
 	}
 
+	for _, warning := range nav.Warnings() {
+		log.Printf("Warning: docs.json %s", warning)
+	}
+
 	return &nav, nil
 }
 
-func (df *DocumentationFetcher) ExtractDocuments(nav *DocsNavigation) ([]*Document, error) {
+// extractionRun carries state shared by every page of one ExtractDocuments call.
+type extractionRun struct {
+	tab     string
+	history map[string]*CommitInfo
+	// ids maps each document ID to the navigation location that claimed it
+	ids map[string]string
+	// referenced holds every normalized page path navigation points at,
+	// found or not, for the orphan scan
+	referenced map[string]bool
+	// order numbers pages in navigation order
+	order  int
+	report *ExtractionReport
+}
+
+// ExtractDocuments reads every Talos page referenced by navigation. The
+// report lists pages that were skipped and files navigation doesn't reach.
+func (df *DocumentationFetcher) ExtractDocuments(nav *DocsNavigation, progress ProgressFunc) ([]*Document, *ExtractionReport, error) {
 	var documents []*Document
 	startTime := time.Now()
 
 	log.Printf("Starting document extraction (Talos docs only)...")
 
+	run := &extractionRun{
+		ids:        make(map[string]string),
+		referenced: make(map[string]bool),
+		report:     newExtractionReport(),
+	}
+	run.report.NavigationWarnings = append(run.report.NavigationWarnings, nav.Warnings()...)
+
+	history, err := df.loadCommitHistory()
+	if err != nil {
+		log.Printf("Warning: commit history unavailable, falling back to file times: %v", err)
+	} else {
+		run.history = history
+	}
+
 	for _, tab := range nav.Navigation.Tabs {
 		// Only process the "Talos" tab
-		if tab.Tab != "Talos" {
+		if tab.Tab != talosTab {
 			log.Printf("Skipping tab: %s (only processing Talos docs)", tab.Tab)
 			continue
 		}
@@ -223,66 +297,81 @@ func (df *DocumentationFetcher) ExtractDocuments(nav *DocsNavigation) ([]*Docume
 		log.Printf("Processing Talos tab with %d versions", len(tab.Versions))
 		for _, version := range tab.Versions {
 			versionStart := time.Now()
-			tabDocs := df.extractDocumentsFromVersion(tab.Tab, version.Version, version.Groups)
+			progress.report("extracting version %s", version.Version)
+			run.tab = tab.Tab
+			tabDocs := df.extractDocumentsFromVersion(run, version.Version, version.Groups)
 			documents = append(documents, tabDocs...)
 			log.Printf("  %s: extracted %d documents in %v", version.Version, len(tabDocs), time.Since(versionStart))
 		}
 	}
 
-	log.Printf("Extraction complete: %d documents in %v", len(documents), time.Since(startTime))
-	return documents, nil
+	run.report.OrphanFiles = append(run.report.OrphanFiles, df.findOrphanFiles(run.referenced)...)
+	checkPublicURLs(documents, nav.Redirects, run.report)
+	df.resolveLinks(documents, nav.Redirects, run.report)
+	run.report.Documents = len(documents)
+	run.report.GeneratedAt = time.Now()
+
+	log.Printf("Extraction complete: %d documents in %v (%d issues)", len(documents), time.Since(startTime), run.report.IssueCount())
+	return documents, run.report, nil
 }
 
-func (df *DocumentationFetcher) extractDocumentsFromVersion(tab, version string, groups []Group) []*Document {
+func (df *DocumentationFetcher) extractDocumentsFromVersion(run *extractionRun, version string, groups []Group) []*Document {
 	var documents []*Document
 
 	for _, group := range groups {
-		groupDocs := df.extractDocumentsFromGroup(tab, version, group.Group, "", group.Pages)
+		groupDocs := df.extractDocumentsFromGroup(run, version, []string{group.Group}, "", group.Pages)
 		documents = append(documents, groupDocs...)
 	}
 
 	return documents
 }
 
-func (df *DocumentationFetcher) extractDocumentsFromGroup(tab, version, groupName, platform string, pages []PageItem) []*Document {
+// extractDocumentsFromGroup extracts a group's pages; groups is the trail of
+// group names down to it, outermost first.
+func (df *DocumentationFetcher) extractDocumentsFromGroup(run *extractionRun, version string, groups []string, platform string, pages []PageItem) []*Document {
 	var documents []*Document
 
 	for _, page := range pages {
-		switch p := page.(type) {
-		case string:
+		switch page.Kind {
+		case PageItemPage:
 			// Direct page reference
-			doc := df.extractDocument(tab, version, groupName, platform, p)
+			doc := df.extractDocument(run, version, groups, platform, page.Page)
 			if doc != nil {
 				documents = append(documents, doc)
 			}
-		case map[string]interface{}:
+		case PageItemGroup:
 			// Nested group
-			if nestedGroup, ok := p["group"].(string); ok {
-				if nestedPages, ok := p["pages"].([]interface{}); ok {
-					// Convert pages to PageItem slice
-					var pageItems []PageItem
-					for _, np := range nestedPages {
-						pageItems = append(pageItems, np)
-					}
-					nestedDocs := df.extractDocumentsFromGroup(tab, version, nestedGroup, platform, pageItems)
-					documents = append(documents, nestedDocs...)
-				}
-			}
-		case Page:
-			doc := df.extractDocument(tab, version, groupName, platform, p.Page)
-			if doc != nil {
-				documents = append(documents, doc)
-			}
-		case NestedGroup:
-			nestedDocs := df.extractDocumentsFromGroup(tab, version, p.Group, platform, p.Pages)
+			nested := append(append([]string(nil), groups...), page.Group.Group)
+			nestedDocs := df.extractDocumentsFromGroup(run, version, nested, platform, page.Group.Pages)
 			documents = append(documents, nestedDocs...)
+		case PageItemLink:
+			// External links have no local content to index
+		default:
+			// Already reported by DocsNavigation.Warnings
 		}
 	}
 
 	return documents
 }
 
-func (df *DocumentationFetcher) extractDocument(tab, version, groupName, platform, pagePath string) *Document {
+func (df *DocumentationFetcher) extractDocument(run *extractionRun, version string, groups []string, platform, pagePath string) *Document {
+	pagePath = normalizePagePath(pagePath)
+	groupName := groups[len(groups)-1]
+
+	// IDs come from the page path, so the only way to collide is the same
+	// page being listed twice; keep the first listing
+	id := documentID(version, pagePath)
+	location := fmt.Sprintf("%s/%s/%s", run.tab, version, groupName)
+	issue := PageIssue{ID: id, Path: pagePath, Version: version, Location: location}
+	run.referenced[pagePath] = true
+	if first, exists := run.ids[id]; exists {
+		log.Printf("Warning: duplicate document ID %s (listed in %s and %s), keeping the first", id, first, location)
+		issue.Detail = fmt.Sprintf("already listed in %s", first)
+		run.report.DuplicateIDs = append(run.report.DuplicateIDs, issue)
+		return nil
+	}
+	run.ids[id] = location
+
 	// Convert page path to file path
 	filePath := filepath.Join(df.localPath, "public", pagePath+".mdx")
 	
@@ -294,21 +383,30 @@ func (df *DocumentationFetcher) extractDocument(tab, version, groupName, platfor
 	// Read file content
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		// File doesn't exist or can't be read - skip silently
+		if os.IsNotExist(err) {
+			issue.Detail = "no .mdx or .md file"
+		} else {
+			issue.Detail = err.Error()
+		}
+		run.report.MissingFiles = append(run.report.MissingFiles, issue)
 		return nil
 	}
 
 	// Skip empty files
-	if len(content) == 0 {
+	if isBlank(content) {
+		run.report.EmptyFiles = append(run.report.EmptyFiles, issue)
 		return nil
 	}
 
+	// Broken frontmatter is reported but the page is still indexed
+	if _, err := parseFrontmatter(string(content)); err != nil {
+		issue.Detail = err.Error()
+		run.report.InvalidFrontmatter = append(run.report.InvalidFrontmatter, issue)
+	}
+
 	// Extract title from content or path
 	title := df.extractTitle(string(content), pagePath)
 
-	// Create document ID
-	id := fmt.Sprintf("%s-%s-%s", version, strings.ToLower(groupName), strings.ToLower(filepath.Base(pagePath)))
-
 	// Determine platform from path if not explicitly set
 	if platform == "" {
 		platform = df.extractPlatformFromPath(pagePath)
@@ -318,6 +416,7 @@ func (df *DocumentationFetcher) extractDocument(tab, version, groupName, platfor
 		ID:          id,
 		Title:       title,
 		Content:     string(content),
+		ContentHash: contentHash(string(content)),
 		Path:        pagePath,
 		Version:     version,
 		Section:     groupName,
@@ -325,14 +424,78 @@ func (df *DocumentationFetcher) extractDocument(tab, version, groupName, platfor
 		Tags:        df.extractTags(string(content), pagePath),
 		LastUpdated: df.getFileModTime(filePath),
 		Metadata: map[string]interface{}{
-			"tab":      tab,
+			"tab":      run.tab,
 			"file_path": filePath,
 		},
 	}
 
+	// Prefer the last commit touching the file over mtime, which is just the
+	// clone time after a fresh checkout
+	if relPath, err := filepath.Rel(df.localPath, filePath); err == nil {
+		if commit := run.history[filepath.ToSlash(relPath)]; commit != nil {
+			doc.LastUpdated = commit.Date
+			doc.LastCommit = commit
+		}
+	}
+
+	if df.publicURL != "" {
+		doc.URL = expandPublicURL(df.publicURL, pagePath, version)
+	}
+
+	run.order++
+	doc.Navigation = groups
+	doc.NavigationOrder = run.order
+	doc.Links = parseLinks(doc.Content, pagePath, df.publicURL)
+
+	if isReleaseNotesPage(doc) {
+		doc.ReleaseNotes = parseReleaseNotes(doc)
+	}
+	if isConfigReferencePage(doc) {
+		doc.ConfigFields = parseConfigFields(doc)
+	}
+	if isCLIReferencePage(doc) {
+		doc.TalosctlCommands = parseTalosctlCommands(doc)
+	}
+
 	return doc
 }
 
+// contentHash fingerprints page content so syncs can tell which pages changed.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizePagePath turns a docs.json page reference or a user-supplied path
+// into the canonical form stored on Document.Path.
+func normalizePagePath(pagePath string) string {
+	pagePath = strings.Trim(strings.TrimSpace(pagePath), "/")
+	for _, ext := range []string{".mdx", ".md"} {
+		pagePath = strings.TrimSuffix(pagePath, ext)
+	}
+	return pagePath
+}
+
+// crossVersionPath drops the version segment from a page path (e.g.
+// talos/v1.11/networking/vip becomes talos/networking/vip), so the same page
+// can be matched across versions.
+func crossVersionPath(pagePath, version string) string {
+	segments := strings.Split(normalizePagePath(pagePath), "/")
+	for i, segment := range segments {
+		if segment == version {
+			return strings.Join(append(segments[:i:i], segments[i+1:]...), "/")
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// documentID derives a stable ID from the version and normalized page path.
+// Unlike titles or basenames, the path is unique within a version, so IDs
+// stay the same across syncs and never collide for pages sharing a name.
+func documentID(version, pagePath string) string {
+	return version + "/" + normalizePagePath(pagePath)
+}
+
 func (df *DocumentationFetcher) extractTitle(content, pagePath string) string {
 	lines := strings.Split(content, "\n")
 	for _, line := range lines {
@@ -414,5 +577,13 @@ func (df *DocumentationFetcher) Stop() {
 }
 
 func (df *DocumentationFetcher) ForceSync() error {
-	return df.pollForUpdates()
+	if err := df.pollForUpdates(); err != nil {
+		return err
+	}
+
+	// Page dates come from local history only, so complete it while online
+	if err := df.deepenShallowHistory(); err != nil {
+		log.Printf("Warning: %v, using local history", err)
+	}
+	return nil
 }
\ No newline at end of file
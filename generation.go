@@ -0,0 +1,631 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+const (
+	// generationsDir holds one directory per index generation
+	generationsDir = "generations"
+
+	// generationInfoFile holds a generation's GenerationInfo
+	generationInfoFile = "generation.json"
+
+	// indexLockFile is flocked by the process writing to an index directory
+	indexLockFile = "index.lock"
+
+	// documentSchemaVersion is bumped whenever extraction adds or changes
+	// the fields stored on documents (parsed references, links, navigation
+	// trails, URLs). Indexes built with another version are rebuilt at
+	// startup rather than served with those fields missing.
+	documentSchemaVersion = 5
+)
+
+// testHookPublish, when set, is called at each step of promoting and
+// publishing a generation, so tests can simulate a crash between them.
+var testHookPublish func(step string)
+
+func publishStep(step string) {
+	if testHookPublish != nil {
+		testHookPublish(step)
+	}
+}
+
+const (
+	generationSourceSync     = "sync"
+	generationSourceBundle   = "bundle"
+	generationSourceMigrated = "migrated"
+)
+
+// GenerationInfo describes a retained index generation.
+type GenerationInfo struct {
+	ID            string    `json:"id"`
+	CommitSHA     string    `json:"commit_sha,omitempty"`
+	DocumentCount int       `json:"document_count"`
+	BuiltAt       time.Time `json:"built_at"`
+	Source        string    `json:"source"`
+	// DocumentSchema is the documentSchemaVersion the documents were
+	// extracted with; 0 for generations built before it was recorded
+	DocumentSchema int  `json:"document_schema"`
+	Current        bool `json:"current"`
+}
+
+// indexGeneration is an immutable index: a bleve index plus the document
+// store, taxonomy and path lookup built alongside it. Rebuilds create a new
+// generation and publish it by swapping SearchEngine.current, so readers
+// never wait on a rebuild. A replaced generation is closed once the last
+// search still using it releases it.
+type indexGeneration struct {
+	id        string
+	path      string
+	index     bleve.Index
+	documents map[string]*Document
+	paths     map[string][]string // normalized path -> document IDs, newest version first
+	backlinks map[string][]string // document ID -> IDs of the documents linking to it
+	taxonomy  *ContentTaxonomy
+	info      GenerationInfo
+
+	mu      sync.Mutex
+	refs    int
+	retired bool
+	closed  bool
+	remove  bool // delete the directory once closed
+}
+
+// newGeneration wraps an open index and its documents. documents must not be
+// modified afterwards.
+func newGeneration(info GenerationInfo, path string, idx bleve.Index, documents map[string]*Document) *indexGeneration {
+	taxonomy := newContentTaxonomy()
+	paths := make(map[string][]string)
+	for docID, doc := range documents {
+		updateTaxonomy(taxonomy, doc)
+		pagePath := normalizePagePath(doc.Path)
+		paths[pagePath] = append(paths[pagePath], docID)
+	}
+	for _, ids := range paths {
+		sort.Slice(ids, func(i, j int) bool {
+			return compareVersions(documents[ids[i]].Version, documents[ids[j]].Version) > 0
+		})
+	}
+
+	return &indexGeneration{
+		id:        info.ID,
+		info:      info,
+		path:      path,
+		index:     idx,
+		documents: documents,
+		paths:     paths,
+		backlinks: buildBacklinks(documents),
+		taxonomy:  taxonomy,
+	}
+}
+
+// acquire pins the generation's bleve index for a search. It fails if the
+// generation was already closed, in which case the caller reloads
+// SearchEngine.current and tries again.
+func (g *indexGeneration) acquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return false
+	}
+	g.refs++
+	return true
+}
+
+func (g *indexGeneration) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.refs--
+	g.closeIfDrained()
+}
+
+// retire marks a generation as replaced; it closes as soon as no search
+// holds it. With remove set its directory is deleted after closing.
+func (g *indexGeneration) retire(remove bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.retired = true
+	if remove && !g.remove {
+		g.remove = true
+		// Retired earlier and already closed: nothing left to wait for
+		if g.closed {
+			g.removeDir()
+			return
+		}
+	}
+	g.closeIfDrained()
+}
+
+// closeIfDrained must be called with g.mu held.
+func (g *indexGeneration) closeIfDrained() {
+	if !g.retired || g.closed || g.refs > 0 {
+		return
+	}
+	g.closed = true
+
+	if err := g.index.Close(); err != nil {
+		log.Printf("Warning: failed to close index generation %s: %v", g.id, err)
+	}
+	log.Printf("Closed index generation %s", g.id)
+
+	if g.remove {
+		g.removeDir()
+	}
+}
+
+func (g *indexGeneration) removeDir() {
+	if err := os.RemoveAll(g.path); err != nil {
+		log.Printf("Warning: failed to remove index generation %s: %v", g.id, err)
+	}
+}
+
+func newGenerationID() string {
+	return time.Now().UTC().Format("20060102T150405.000000000Z")
+}
+
+func (se *SearchEngine) generationPath(id string) string {
+	return filepath.Join(se.indexPath, generationsDir, id)
+}
+
+// openGenerations opens the current generation named by the manifest.
+// Indices from before generations existed are migrated first. If the current
+// generation is missing or damaged, the most recently published one that
+// opens and checks out is used instead, then any other generation on disk.
+// Nothing is deleted here, so a generation that fails is left for
+// inspection. With nothing usable an empty generation is created. Read-only
+// engines skip the migration and never write the manifest.
+func (se *SearchEngine) openGenerations() (*indexGeneration, error) {
+	if se.readOnly {
+		return se.openPublishedGeneration()
+	}
+	if err := os.MkdirAll(filepath.Join(se.indexPath, generationsDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create generations directory: %w", err)
+	}
+	if err := se.migrateLegacyIndex(); err != nil {
+		return nil, fmt.Errorf("failed to migrate legacy index: %w", err)
+	}
+
+	manifest, err := se.readManifest()
+	if err != nil {
+		log.Printf("Warning: %v, recovering from the generations on disk", err)
+		manifest = &indexManifest{}
+	}
+
+	candidates := append([]string{manifest.Current}, manifest.Generations...)
+	candidates = append(candidates, se.listGenerations()...)
+
+	failed := make(map[string]bool)
+	for _, id := range candidates {
+		if id == "" || failed[id] {
+			continue
+		}
+
+		generation, err := se.openGeneration(id)
+		if err == nil {
+			if err = generation.checkConsistency(); err != nil {
+				generation.index.Close()
+			}
+		}
+		if err != nil {
+			log.Printf("Warning: skipping index generation %s: %v", id, err)
+			failed[id] = true
+			continue
+		}
+
+		if manifest.Current != "" && id != manifest.Current {
+			log.Printf("Recovered index generation %s in place of %s", id, manifest.Current)
+		}
+
+		var published []string
+		for _, other := range manifest.Generations {
+			if !failed[other] {
+				published = append(published, other)
+			}
+		}
+		if err := se.writeManifest(id, generation.info.DocumentSchema, published); err != nil {
+			generation.index.Close()
+			return nil, err
+		}
+		return generation, nil
+	}
+
+	log.Printf("No existing index found, creating empty index")
+	id := newGenerationID()
+	idx, err := bleve.New(se.generationPath(id), bleve.NewIndexMapping())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index: %w", err)
+	}
+	if err := se.writeManifest(id, documentSchemaVersion, nil); err != nil {
+		idx.Close()
+		return nil, err
+	}
+	info := GenerationInfo{ID: id, BuiltAt: time.Now().UTC(), Source: generationSourceSync, DocumentSchema: documentSchemaVersion}
+	return newGeneration(info, se.generationPath(id), idx, make(map[string]*Document)), nil
+}
+
+// openPublishedGeneration opens the current generation, or the most
+// recently published one that opens, without changing anything on disk.
+func (se *SearchEngine) openPublishedGeneration() (*indexGeneration, error) {
+	manifest, err := se.readManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range append([]string{manifest.Current}, manifest.Generations...) {
+		if id == "" {
+			continue
+		}
+		generation, err := se.openGeneration(id)
+		if err != nil {
+			log.Printf("Warning: skipping index generation %s: %v", id, err)
+			continue
+		}
+		return generation, nil
+	}
+	return nil, fmt.Errorf("no published index generation in %s", se.indexPath)
+}
+
+// openGeneration opens a generation directory and its document store. A
+// missing store is not an error: indices built before the store existed
+// simply fall back to reading stored fields from bleve.
+func (se *SearchEngine) openGeneration(id string) (*indexGeneration, error) {
+	path := se.generationPath(id)
+
+	// Read-only so nothing else needs the index's write lock
+	idx, err := bleve.OpenUsing(path, map[string]interface{}{"read_only": true})
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := readDocumentStore(path)
+	if os.IsNotExist(err) {
+		documents, err = nil, nil
+	}
+	if err != nil {
+		idx.Close()
+		return nil, fmt.Errorf("failed to load document store: %w", err)
+	}
+
+	info, err := se.readGenerationInfo(id)
+	if err != nil {
+		idx.Close()
+		return nil, err
+	}
+	if info.DocumentCount == 0 {
+		info.DocumentCount = len(documents)
+	}
+
+	docCount, _ := idx.DocCount()
+	log.Printf("Opened index generation %s with %d documents (%d in document store)", id, docCount, len(documents))
+	return newGeneration(info, path, idx, documentsByID(documents)), nil
+}
+
+// readGenerationInfo reads a generation's metadata. Generations from before
+// metadata was recorded get what can be inferred from the directory.
+func (se *SearchEngine) readGenerationInfo(id string) (GenerationInfo, error) {
+	path := se.generationPath(id)
+
+	data, err := os.ReadFile(filepath.Join(path, generationInfoFile))
+	if os.IsNotExist(err) {
+		info := GenerationInfo{ID: id, Source: generationSourceMigrated}
+		if stat, err := os.Stat(path); err == nil {
+			info.BuiltAt = stat.ModTime().UTC()
+		}
+		return info, nil
+	}
+	if err != nil {
+		return GenerationInfo{}, fmt.Errorf("failed to read %s: %w", generationInfoFile, err)
+	}
+
+	var info GenerationInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return GenerationInfo{}, fmt.Errorf("failed to decode %s: %w", generationInfoFile, err)
+	}
+	info.ID = id
+	return info, nil
+}
+
+func writeGenerationInfo(dir string, info GenerationInfo) error {
+	info.Current = false
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", generationInfoFile, err)
+	}
+	return os.WriteFile(filepath.Join(dir, generationInfoFile), data, 0644)
+}
+
+// ListGenerations describes the generations on disk, newest first.
+func (se *SearchEngine) ListGenerations() []GenerationInfo {
+	current := se.current.Load().id
+
+	var generations []GenerationInfo
+	for _, id := range se.listGenerations() {
+		info, err := se.readGenerationInfo(id)
+		if err != nil {
+			log.Printf("Warning: skipping index generation %s: %v", id, err)
+			continue
+		}
+		info.Current = id == current
+		generations = append(generations, info)
+	}
+	return generations
+}
+
+// migrateLegacyIndex moves the active and backup directories used before
+// generations into generations/, backup first so active ends up newest.
+func (se *SearchEngine) migrateLegacyIndex() error {
+	os.RemoveAll(filepath.Join(se.indexPath, "staging"))
+
+	for _, legacy := range []string{"backup", "active"} {
+		legacyPath := filepath.Join(se.indexPath, legacy)
+		if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+			continue
+		}
+
+		id := newGenerationID()
+		if err := os.Rename(legacyPath, se.generationPath(id)); err != nil {
+			return err
+		}
+		if err := syncDir(filepath.Join(se.indexPath, generationsDir)); err != nil {
+			return err
+		}
+		log.Printf("Migrated legacy %s index to generation %s", legacy, id)
+		if legacy == "active" {
+			if err := se.writeManifest(id, 0, se.listGenerations()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listGenerations returns the generation IDs on disk, newest first.
+func (se *SearchEngine) listGenerations() []string {
+	entries, err := os.ReadDir(filepath.Join(se.indexPath, generationsDir))
+	if err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids
+}
+
+// promoteStaging moves a finished staging directory into a new generation,
+// opens it and publishes it. With checkHealth set the generation must also
+// pass checkHealth, otherwise it is discarded and the current generation
+// stays in place. Callers must hold se.writeMu.
+func (se *SearchEngine) promoteStaging(stagingPath string, info GenerationInfo, checkHealth bool) (*GenerationInfo, error) {
+	info.ID = newGenerationID()
+	if err := writeGenerationInfo(stagingPath, info); err != nil {
+		return nil, err
+	}
+	publishStep("staged")
+
+	// Flush the build before it appears under generations/, so a crash
+	// can't leave a generation directory with missing or torn files
+	if err := syncTree(stagingPath); err != nil {
+		return nil, fmt.Errorf("failed to flush staging index: %w", err)
+	}
+	publishStep("flushed")
+
+	path := se.generationPath(info.ID)
+	if err := os.Rename(stagingPath, path); err != nil {
+		return nil, fmt.Errorf("failed to move staging index to generation %s: %w", info.ID, err)
+	}
+	if err := syncDir(filepath.Join(se.indexPath, generationsDir)); err != nil {
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("failed to flush generations directory: %w", err)
+	}
+	publishStep("renamed")
+
+	generation, err := se.openGeneration(info.ID)
+	if err != nil {
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("failed to open index generation %s: %w", info.ID, err)
+	}
+
+	if checkHealth {
+		if err := se.checkHealth(generation); err != nil {
+			generation.index.Close()
+			os.RemoveAll(path)
+			current := se.current.Load()
+			log.Printf("Index generation %s failed its health check, staying on %s: %v", info.ID, current.id, err)
+			return nil, fmt.Errorf("new index failed health check, kept generation %s: %w", current.id, err)
+		}
+	}
+
+	if err := se.publish(generation); err != nil {
+		generation.index.Close()
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("failed to publish index generation %s: %w", info.ID, err)
+	}
+
+	published := generation.info
+	published.Current = true
+	return &published, nil
+}
+
+// checkHealth rejects a generation whose index and store disagree, that is
+// empty, or whose document count dropped by more than maxDocumentDrop
+// percent compared to the current generation.
+func (se *SearchEngine) checkHealth(candidate *indexGeneration) error {
+	count := len(candidate.documents)
+	if count == 0 {
+		return fmt.Errorf("generation has no documents")
+	}
+	if err := candidate.checkConsistency(); err != nil {
+		return err
+	}
+
+	previous := len(se.current.Load().documents)
+	if previous > 0 && se.maxDocumentDrop > 0 && count < previous {
+		drop := float64(previous-count) / float64(previous) * 100
+		if drop > se.maxDocumentDrop {
+			return fmt.Errorf("document count dropped %.1f%% (%d to %d), more than the allowed %.1f%%", drop, previous, count, se.maxDocumentDrop)
+		}
+	}
+	return nil
+}
+
+// checkConsistency verifies that the bleve index, the document store and
+// generation.json agree on the document count, which a generation cut short
+// by a crash usually doesn't. Generations without a store are not checked.
+func (g *indexGeneration) checkConsistency() error {
+	count := len(g.documents)
+	if count == 0 {
+		return nil
+	}
+
+	docCount, err := g.index.DocCount()
+	if err != nil {
+		return fmt.Errorf("failed to count indexed documents: %w", err)
+	}
+	if int(docCount) != count {
+		return fmt.Errorf("index has %d documents but the document store has %d", docCount, count)
+	}
+	if g.info.DocumentCount != count {
+		return fmt.Errorf("%s records %d documents but the document store has %d", generationInfoFile, g.info.DocumentCount, count)
+	}
+	return nil
+}
+
+// Rollback switches to a retained generation, by default the newest
+// published one older than the current generation.
+func (se *SearchEngine) Rollback(id string) (*GenerationInfo, error) {
+	if se.readOnly {
+		return nil, errReadOnlyIndex
+	}
+	se.writeMu.Lock()
+	defer se.writeMu.Unlock()
+
+	current := se.current.Load()
+	if id == "" {
+		manifest, err := se.readManifest()
+		if err != nil {
+			return nil, err
+		}
+		published := make(map[string]bool, len(manifest.Generations))
+		for _, candidate := range manifest.Generations {
+			published[candidate] = true
+		}
+
+		for _, candidate := range se.listGenerations() {
+			if candidate < current.id && published[candidate] {
+				id = candidate
+				break
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("no generation older than %s is retained", current.id)
+		}
+	}
+	if id == current.id {
+		return nil, fmt.Errorf("generation %s is already current", id)
+	}
+	if _, err := os.Stat(se.generationPath(id)); err != nil {
+		return nil, fmt.Errorf("generation %s is not retained", id)
+	}
+
+	generation, err := se.openGeneration(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index generation %s: %w", id, err)
+	}
+	if err := se.publish(generation); err != nil {
+		generation.index.Close()
+		return nil, fmt.Errorf("failed to publish index generation %s: %w", id, err)
+	}
+
+	log.Printf("Rolled back from index generation %s to %s", current.id, id)
+	info := generation.info
+	info.Current = true
+	return &info, nil
+}
+
+// publish makes generation current and retires the one it replaces. The
+// current generation plus the most recently published others up to
+// retainedGenerations stay on disk; the rest, including directories never
+// published, are removed once their searches drain. The manifest is written
+// before readers switch over, so after a crash startup opens either the old
+// or the new generation. Callers must hold se.writeMu.
+func (se *SearchEngine) publish(generation *indexGeneration) error {
+	manifest, err := se.readManifest()
+	if err != nil {
+		log.Printf("Warning: %v, rewriting it", err)
+		manifest = &indexManifest{}
+	}
+
+	keep := map[string]bool{generation.id: true}
+	var retained []string
+	for _, id := range manifest.Generations {
+		if len(keep) >= se.retainedGenerations {
+			break
+		}
+		if keep[id] {
+			continue
+		}
+		if _, err := os.Stat(se.generationPath(id)); err != nil {
+			continue
+		}
+		keep[id] = true
+		retained = append(retained, id)
+	}
+
+	publishStep("manifest")
+	if err := se.writeManifest(generation.id, generation.info.DocumentSchema, retained); err != nil {
+		return err
+	}
+	publishStep("published")
+
+	previous := se.current.Swap(generation)
+	se.open[generation.id] = generation
+	if previous != nil {
+		previous.retire(false)
+	}
+
+	for _, id := range se.listGenerations() {
+		if keep[id] {
+			continue
+		}
+		if open, exists := se.open[id]; exists {
+			open.retire(true)
+			delete(se.open, id)
+			continue
+		}
+		if err := os.RemoveAll(se.generationPath(id)); err != nil {
+			log.Printf("Warning: failed to remove index generation %s: %v", id, err)
+		}
+	}
+
+	log.Printf("Published index generation %s", generation.id)
+	return nil
+}
+
+// acquireCurrent returns the current generation pinned for use of its bleve
+// index; callers must release it.
+func (se *SearchEngine) acquireCurrent() *indexGeneration {
+	for {
+		generation := se.current.Load()
+		if generation.acquire() {
+			return generation
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testDocuments(count int) []*Document {
+	documents := make([]*Document, 0, count)
+	for i := 0; i < count; i++ {
+		documents = append(documents, &Document{
+			ID:      fmt.Sprintf("v1.11/talos/v1.11/page-%d", i),
+			Title:   fmt.Sprintf("Page %d", i),
+			Content: fmt.Sprintf("# Page %d\n\nKubernetes networking page number %d.\n", i, i),
+			Path:    fmt.Sprintf("talos/v1.11/page-%d", i),
+			Version: "v1.11",
+			Section: "Networking",
+		})
+	}
+	return documents
+}
+
+func openTestEngine(t *testing.T, dir string) *SearchEngine {
+	t.Helper()
+	se, err := NewSearchEngine(dir, 20, 300, 3, 20)
+	if err != nil {
+		t.Fatalf("failed to open search engine: %v", err)
+	}
+	return se
+}
+
+// crash simulates the process dying: nothing is cleaned up or closed
+// except the writer lock, which the kernel would drop with the process.
+func crash(se *SearchEngine) {
+	se.lock.Close()
+	se.lock = nil
+}
+
+func TestPublishCrashRecovery(t *testing.T) {
+	tests := []struct {
+		step string
+		// tornManifest leaves a partly written manifest.tmp behind, as a
+		// crash during the manifest write would
+		tornManifest bool
+		// published is whether the new generation was published before
+		// the crash, so recovery must pick it rather than the old one
+		published bool
+	}{
+		{step: "staged"},
+		{step: "flushed"},
+		{step: "renamed"},
+		{step: "manifest"},
+		{step: "manifest", tornManifest: true},
+		{step: "published", published: true},
+	}
+
+	for _, tt := range tests {
+		name := tt.step
+		if tt.tornManifest {
+			name += "/torn"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			se := openTestEngine(t, dir)
+			if err := se.IndexDocuments(testDocuments(3), "old", nil); err != nil {
+				t.Fatalf("failed to index first generation: %v", err)
+			}
+			previous := se.current.Load().id
+
+			crashed := func() (crashed bool) {
+				testHookPublish = func(step string) {
+					if step == tt.step {
+						panic("crash at " + step)
+					}
+				}
+				defer func() {
+					testHookPublish = nil
+					crashed = recover() != nil
+				}()
+				se.IndexDocuments(testDocuments(4), "new", nil)
+				return false
+			}()
+			if !crashed {
+				t.Fatalf("publishing never reached step %s", tt.step)
+			}
+			if tt.tornManifest {
+				if err := os.WriteFile(filepath.Join(dir, manifestFile+".tmp"), []byte(`{"current": "`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			crash(se)
+
+			recovered := openTestEngine(t, dir)
+			defer recovered.Close()
+
+			current := recovered.current.Load()
+			wantCount, wantCommit := 3, "old"
+			if tt.published {
+				wantCount, wantCommit = 4, "new"
+			} else if current.id != previous {
+				t.Errorf("recovered generation %s, want the last published %s", current.id, previous)
+			}
+			if len(current.documents) != wantCount || current.info.CommitSHA != wantCommit {
+				t.Errorf("recovered generation has %d documents from commit %q, want %d from %q",
+					len(current.documents), current.info.CommitSHA, wantCount, wantCommit)
+			}
+
+			manifest, err := recovered.readManifest()
+			if err != nil {
+				t.Fatalf("failed to read manifest after recovery: %v", err)
+			}
+			if manifest.Current != current.id {
+				t.Errorf("manifest names %s as current, want %s", manifest.Current, current.id)
+			}
+
+			// The recovered engine must be able to publish again
+			if err := recovered.IndexDocuments(testDocuments(5), "next", nil); err != nil {
+				t.Fatalf("failed to index after recovery: %v", err)
+			}
+		})
+	}
+}
+
+func TestIndexLock(t *testing.T) {
+	dir := t.TempDir()
+	se := openTestEngine(t, dir)
+	if err := se.IndexDocuments(testDocuments(3), "abc", nil); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+
+	if second, err := NewSearchEngine(dir, 20, 300, 3, 20); err == nil {
+		second.Close()
+		t.Fatal("a second writer opened a locked index")
+	}
+
+	// A build in progress and the manifest must survive a reader
+	stagingFile := filepath.Join(dir, "staging", "in-progress")
+	if err := os.MkdirAll(filepath.Dir(stagingFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stagingFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifestBefore, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := OpenSearchEngineReadOnly(dir, 20, 300)
+	if err != nil {
+		t.Fatalf("failed to open index read-only next to a writer: %v", err)
+	}
+	if got := reader.current.Load().info.CommitSHA; got != "abc" {
+		t.Errorf("read-only engine opened commit %q, want abc", got)
+	}
+	if generations := reader.ListGenerations(); len(generations) != 2 || !generations[0].Current {
+		t.Errorf("read-only engine listed %+v, want the published generation first and current", generations)
+	}
+	if err := reader.IndexDocuments(testDocuments(1), "x", nil); err != errReadOnlyIndex {
+		t.Errorf("read-only IndexDocuments returned %v, want %v", err, errReadOnlyIndex)
+	}
+	if _, err := reader.Rollback(""); err != errReadOnlyIndex {
+		t.Errorf("read-only Rollback returned %v, want %v", err, errReadOnlyIndex)
+	}
+	reader.Close()
+
+	if _, err := os.Stat(stagingFile); err != nil {
+		t.Errorf("read-only open removed the staging build: %v", err)
+	}
+	manifestAfter, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil || string(manifestAfter) != string(manifestBefore) {
+		t.Errorf("read-only open rewrote the manifest")
+	}
+
+	se.Close()
+	reopened := openTestEngine(t, dir)
+	reopened.Close()
+}
+
+func TestDocumentSchema(t *testing.T) {
+	dir := t.TempDir()
+	se := openTestEngine(t, dir)
+	if err := se.IndexDocuments(testDocuments(3), "abc", nil); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+	if schema, err := se.DocumentSchema(); err != nil || schema != documentSchemaVersion {
+		t.Fatalf("DocumentSchema() = %d, %v, want %d", schema, err, documentSchemaVersion)
+	}
+
+	// A generation built before the schema was recorded has none in its
+	// generation.json; reopening must report it as outdated
+	current := se.current.Load()
+	info := current.info
+	info.DocumentSchema = 0
+	if err := writeGenerationInfo(current.path, info); err != nil {
+		t.Fatal(err)
+	}
+	se.Close()
+
+	reopened := openTestEngine(t, dir)
+	defer reopened.Close()
+	if schema, err := reopened.DocumentSchema(); err != nil || schema != 0 {
+		t.Errorf("DocumentSchema() of a pre-schema index = %d, %v, want 0", schema, err)
+	}
+
+	if err := reopened.IndexDocuments(testDocuments(3), "def", nil); err != nil {
+		t.Fatalf("failed to rebuild: %v", err)
+	}
+	if schema, _ := reopened.DocumentSchema(); schema != documentSchemaVersion {
+		t.Errorf("DocumentSchema() after rebuild = %d, want %d", schema, documentSchemaVersion)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CommitInfo is the last commit that touched a documentation file.
+type CommitInfo struct {
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"`
+	Message     string    `json:"message"`
+	// Approximate is set when history ran out (shallow boundary or depth
+	// limit) before the file's real last change was found; the commit is
+	// then the oldest one available, not necessarily the one that changed it.
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// openRepository returns the cloned repository, opening an existing checkout
+// from disk when the fetcher skipped git operations at startup.
+func (df *DocumentationFetcher) openRepository() (*git.Repository, error) {
+	if df.gitRepo != nil {
+		return df.gitRepo, nil
+	}
+
+	repo, err := git.PlainOpen(df.localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	return repo, nil
+}
+
+// loadCommitHistory maps repo-relative file paths under public/ to the last
+// commit touching them, using only the history available locally. It never
+// touches the network: extraction runs offline and on every diagnostics
+// refresh, so shallow clones are deepened by the sync path instead (see
+// deepenShallowHistory).
+func (df *DocumentationFetcher) loadCommitHistory() (map[string]*CommitInfo, error) {
+	start := time.Now()
+
+	repo, err := df.openRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	history, walked, truncated, err := walkCommitHistory(repo, head.Hash(), df.historyDepth)
+	if err != nil {
+		return nil, err
+	}
+	if truncated && walked < df.historyDepth {
+		log.Printf("Shallow history (%d of %d commits), older pages are dated approximately until the next sync deepens it", walked, df.historyDepth)
+	}
+
+	log.Printf("Loaded commit history for %d files from %d commits in %v", len(history), walked, time.Since(start))
+	return history, nil
+}
+
+// deepenShallowHistory fetches up to historyDepth commits when the local
+// history is shallower, e.g. a checkout cloned with a smaller depth. It is
+// only called when syncing, where network access is expected.
+func (df *DocumentationFetcher) deepenShallowHistory() error {
+	repo, err := df.openRepository()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	_, walked, truncated, err := walkCommitHistory(repo, head.Hash(), df.historyDepth)
+	if err != nil {
+		return err
+	}
+	if !truncated || walked >= df.historyDepth {
+		return nil
+	}
+
+	log.Printf("Shallow history (%d commits), deepening to %d...", walked, df.historyDepth)
+	if err := df.deepenHistory(repo); err != nil {
+		return fmt.Errorf("failed to deepen history: %w", err)
+	}
+	return nil
+}
+
+func (df *DocumentationFetcher) deepenHistory(repo *git.Repository) error {
+	err := repo.Fetch(&git.FetchOptions{
+		RemoteURL: df.repoURL,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", df.branch, df.branch)),
+		},
+		Depth: df.historyDepth,
+		Tags:  git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// walkCommitHistory follows first parents from head for at most limit
+// commits, recording the newest commit that changed each file under public/.
+// Files never seen changing before the walk stops are attributed to the last
+// commit reached and marked approximate. truncated reports whether the walk
+// stopped early because parents were missing or the limit was hit.
+func walkCommitHistory(repo *git.Repository, head plumbing.Hash, limit int) (history map[string]*CommitInfo, walked int, truncated bool, err error) {
+	history = make(map[string]*CommitInfo)
+
+	commit, err := repo.CommitObject(head)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+
+	for commit != nil {
+		walked++
+
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, walked, false, fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+		}
+
+		var parent *object.Commit
+		if commit.NumParents() > 0 && walked < limit {
+			// A shallow boundary lists its parent but the object is absent
+			if p, err := commit.Parent(0); err == nil {
+				parent = p
+			}
+		}
+		if parent == nil {
+			truncated = commit.NumParents() > 0
+			attributeRemainingFiles(history, tree, commitInfoFrom(commit, truncated))
+			break
+		}
+
+		parentTree, err := parent.Tree()
+		if err != nil {
+			truncated = true
+			attributeRemainingFiles(history, tree, commitInfoFrom(commit, true))
+			break
+		}
+
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return nil, walked, false, fmt.Errorf("failed to diff %s: %w", commit.Hash, err)
+		}
+
+		var info *CommitInfo
+		for _, change := range changes {
+			name := change.To.Name
+			if name == "" {
+				name = change.From.Name
+			}
+			if !strings.HasPrefix(name, "public/") || history[name] != nil {
+				continue
+			}
+			if info == nil {
+				info = commitInfoFrom(commit, false)
+			}
+			history[name] = info
+		}
+
+		commit = parent
+	}
+
+	return history, walked, truncated, nil
+}
+
+func attributeRemainingFiles(history map[string]*CommitInfo, tree *object.Tree, info *CommitInfo) {
+	tree.Files().ForEach(func(f *object.File) error {
+		if strings.HasPrefix(f.Name, "public/") && history[f.Name] == nil {
+			history[f.Name] = info
+		}
+		return nil
+	})
+}
+
+func commitInfoFrom(commit *object.Commit, approximate bool) *CommitInfo {
+	// Keep only the subject line; bodies add noise to search results
+	message := strings.TrimSpace(commit.Message)
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = strings.TrimSpace(message[:i])
+	}
+
+	return &CommitInfo{
+		SHA:         commit.Hash.String(),
+		Author:      commit.Author.Name,
+		AuthorEmail: commit.Author.Email,
+		Date:        commit.Author.When,
+		Message:     message,
+		Approximate: approximate,
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// linkRankWeight scales how much backlinks lift a search hit: its score
+	// is multiplied by 1 + linkRankWeight*ln(1+backlinks), so a page linked
+	// from 20 others gains about 30% over an unlinked one
+	linkRankWeight = 0.1
+
+	// linkRankCandidates is how many hits per requested result are fetched
+	// for reranking by backlinks
+	linkRankCandidates = 4
+)
+
+var (
+	// markdownLinkPattern matches inline links, [text](href "title"), and
+	// reference definitions, [ref]: href
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)|^\s*\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+"[^"]*")?\s*$`)
+
+	// hrefPattern matches JSX and HTML link attributes, e.g. <Card href="...">
+	hrefPattern = regexp.MustCompile(`\bhref=["{]?["']([^"']+)["']`)
+
+	inlineCodePattern = regexp.MustCompile("`[^`]*`")
+)
+
+// DocumentLink is a link from one page to another indexed page. Links are
+// parsed from the markdown at extraction and resolved against every page
+// extracted alongside it; Target is the linked document's ID, and Broken
+// marks links to a page path that doesn't exist.
+type DocumentLink struct {
+	Href   string `json:"href"`
+	Path   string `json:"path"`
+	Anchor string `json:"anchor,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Line   int    `json:"line"`
+	Target string `json:"target,omitempty"`
+	Broken bool   `json:"broken,omitempty"`
+}
+
+// LinkedDocument is a page on the other end of one or more links, with the
+// lines of the linking page they appear on.
+type LinkedDocument struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Version string   `json:"version"`
+	URI     string   `json:"uri"`
+	URL     string   `json:"url,omitempty"`
+	Lines   []int    `json:"lines"`
+	Anchors []string `json:"anchors,omitempty"`
+}
+
+// parseLinks returns the internal links of a page: root-relative and
+// relative hrefs, and absolute ones into the public docs site. Links in
+// code, images and links to other files such as images are left out.
+func parseLinks(content, pagePath, publicURL string) []DocumentLink {
+	var links []DocumentLink
+	fence := ""
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			continue
+		}
+		line = inlineCodePattern.ReplaceAllString(line, "")
+
+		for _, match := range markdownLinkPattern.FindAllStringSubmatch(line, -1) {
+			if match[1] == "!" {
+				continue
+			}
+			href, text := match[3], match[2]
+			if href == "" {
+				href, text = match[4], ""
+			}
+			if link, ok := internalLink(href, pagePath, publicURL); ok {
+				link.Text = strings.TrimSpace(text)
+				link.Line = i + 1
+				links = append(links, link)
+			}
+		}
+		for _, match := range hrefPattern.FindAllStringSubmatch(line, -1) {
+			if link, ok := internalLink(match[1], pagePath, publicURL); ok {
+				link.Line = i + 1
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// internalLink resolves href, as found on pagePath, to a page path. Links
+// to anchors on the same page, other sites and non-page files aren't
+// internal.
+func internalLink(href, pagePath, publicURL string) (DocumentLink, bool) {
+	link := DocumentLink{Href: href}
+	parsed, err := url.Parse(href)
+	if err != nil || (parsed.Path == "" && parsed.Host == "") {
+		return link, false
+	}
+	target := parsed.Path
+	if parsed.Scheme != "" || parsed.Host != "" {
+		prefix, ok := publicURLPrefix(publicURL)
+		if !ok || !strings.EqualFold(parsed.Host, prefix.Host) || !strings.HasPrefix(target, prefix.Path) {
+			return link, false
+		}
+		target = "/" + strings.TrimPrefix(target, prefix.Path)
+	}
+	if ext := path.Ext(target); ext != "" && ext != ".md" && ext != ".mdx" {
+		return link, false
+	}
+
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(normalizePagePath(pagePath)), target)
+	}
+	link.Path = normalizePagePath(path.Clean("/" + target))
+	link.Anchor = parsed.Fragment
+	return link, link.Path != ""
+}
+
+// publicURLPrefix is the site and path a public URL template puts page paths
+// under, when the template maps page paths directly (i.e. uses {path}).
+func publicURLPrefix(publicURL string) (*url.URL, bool) {
+	before, _, found := strings.Cut(publicURL, "{path}")
+	if !found || strings.Contains(before, "{") {
+		return nil, false
+	}
+	prefix, err := url.Parse(before)
+	if err != nil || prefix.Host == "" {
+		return nil, false
+	}
+	return prefix, true
+}
+
+// resolveLinks points every parsed link at the document it reaches,
+// following docs.json redirects. A link to a path with neither a document,
+// a redirect nor a file in the repository (pages of other products aren't
+// indexed but aren't broken either) is reported as broken.
+func (df *DocumentationFetcher) resolveLinks(documents []*Document, redirects []Redirect, report *ExtractionReport) {
+	byPath := make(map[string]*Document, len(documents))
+	for _, doc := range documents {
+		byPath[normalizePagePath(doc.Path)] = doc
+	}
+	table := newRedirectTable(redirects)
+
+	for _, doc := range documents {
+		for i := range doc.Links {
+			link := &doc.Links[i]
+			if final, hops, err := table.follow("/" + link.Path); err == nil && len(hops) > 0 {
+				if parsed, err := url.Parse(final); err == nil {
+					link.Path = normalizePagePath(parsed.Path)
+				}
+			}
+			if target, exists := byPath[link.Path]; exists {
+				link.Target = target.ID
+				continue
+			}
+			if df.pageFileExists(link.Path) {
+				continue
+			}
+
+			link.Broken = true
+			report.BrokenLinks = append(report.BrokenLinks, PageIssue{
+				ID:       doc.ID,
+				Path:     doc.Path,
+				Version:  doc.Version,
+				Location: link.Href,
+				Detail:   fmt.Sprintf("line %d: no page at /%s", link.Line, link.Path),
+			})
+		}
+	}
+}
+
+func (df *DocumentationFetcher) pageFileExists(pagePath string) bool {
+	base := filepath.Join(df.localPath, "public", filepath.FromSlash(pagePath))
+	for _, candidate := range []string{base + ".mdx", base + ".md", filepath.Join(base, "index.mdx"), filepath.Join(base, "index.md")} {
+		if _, err := os.Stat(candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// buildBacklinks inverts the resolved links of every document into the
+// IDs of the pages linking to each, sorted. Links from a page to itself
+// don't count.
+func buildBacklinks(documents map[string]*Document) map[string][]string {
+	backlinks := make(map[string][]string)
+	for id, doc := range documents {
+		seen := make(map[string]bool)
+		for _, link := range doc.Links {
+			if link.Target == "" || link.Target == id || seen[link.Target] {
+				continue
+			}
+			seen[link.Target] = true
+			backlinks[link.Target] = append(backlinks[link.Target], id)
+		}
+	}
+	for _, sources := range backlinks {
+		sort.Strings(sources)
+	}
+	return backlinks
+}
+
+// linkRankBoost is the factor a hit's score is multiplied by for the pages
+// linking to it.
+func linkRankBoost(backlinks int) float64 {
+	return 1 + linkRankWeight*math.Log1p(float64(backlinks))
+}
+
+// linkedDocument starts the entry for doc in a related-docs listing.
+func linkedDocument(doc *Document) *LinkedDocument {
+	return &LinkedDocument{
+		ID:      doc.ID,
+		Title:   doc.Title,
+		Version: doc.Version,
+		URI:     documentResourceURI(doc),
+		URL:     doc.URL,
+		Lines:   []int{},
+	}
+}
+
+// addLink records one link on a related-docs entry.
+func (d *LinkedDocument) addLink(link DocumentLink) {
+	d.Lines = append(d.Lines, link.Line)
+	if link.Anchor == "" {
+		return
+	}
+	for _, anchor := range d.Anchors {
+		if anchor == link.Anchor {
+			return
+		}
+	}
+	d.Anchors = append(d.Anchors, link.Anchor)
+}
+
+func (s *TalosDocMCPServer) handleGetRelatedDocs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ref, err := request.RequireString("ref")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	doc, _, exists := s.resolveDocumentRef(ref, request.GetString("version", ""))
+	if !exists {
+		return mcp.NewToolResultError(fmt.Sprintf("no document %s; find IDs with search_talos_docs or browse_talos_docs", ref)), nil
+	}
+
+	// Outgoing links in the order the page first links to each target
+	outgoing := []*LinkedDocument{}
+	byTarget := make(map[string]*LinkedDocument)
+	broken := []DocumentLink{}
+	unindexed := 0
+	for _, link := range doc.Links {
+		switch {
+		case link.Broken:
+			broken = append(broken, link)
+		case link.Target == "":
+			unindexed++
+		case link.Target == doc.ID:
+		default:
+			entry, exists := byTarget[link.Target]
+			if !exists {
+				target, indexed := s.searchEngine.GetDocument(link.Target)
+				if !indexed {
+					unindexed++
+					continue
+				}
+				entry = linkedDocument(target)
+				byTarget[link.Target] = entry
+				outgoing = append(outgoing, entry)
+			}
+			entry.addLink(link)
+		}
+	}
+
+	backlinks := []*LinkedDocument{}
+	for _, id := range s.searchEngine.Backlinks(doc.ID) {
+		source, exists := s.searchEngine.GetDocument(id)
+		if !exists {
+			continue
+		}
+		entry := linkedDocument(source)
+		for _, link := range source.Links {
+			if link.Target == doc.ID {
+				entry.addLink(link)
+			}
+		}
+		backlinks = append(backlinks, entry)
+	}
+
+	result := map[string]interface{}{
+		"id":           doc.ID,
+		"title":        doc.Title,
+		"version":      doc.Version,
+		"uri":          documentResourceURI(doc),
+		"url":          doc.URL,
+		"outgoing":     outgoing,
+		"backlinks":    backlinks,
+		"broken_links": broken,
+	}
+	if unindexed > 0 {
+		result["unindexed_links"] = unindexed
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
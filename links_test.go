@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const linksPagePath = "talos/v1.11/networking/vip"
+
+func TestParseLinks(t *testing.T) {
+	data, err := os.ReadFile("testdata/links.mdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+
+	want := []DocumentLink{
+		// Relative to the page's directory, with or without an extension
+		{Href: "multihoming", Path: "talos/v1.11/networking/multihoming", Text: "Multihoming", Line: lineOf(t, content, "[Multihoming](multihoming)")},
+		{Href: "../getting-started/install.mdx", Path: "talos/v1.11/getting-started/install", Text: "the install guide", Line: lineOf(t, content, "[the install guide]")},
+		// Root-relative, keeping the anchor
+		{Href: "/talos/v1.11/networking/kubespan#configuration", Path: "talos/v1.11/networking/kubespan", Anchor: "configuration", Text: "KubeSpan", Line: lineOf(t, content, "[KubeSpan]")},
+		// Absolute links into the public site, whichever product
+		{Href: "https://docs.siderolabs.com/talos/v1.11/getting-started/quickstart", Path: "talos/v1.11/getting-started/quickstart", Text: "quickstart", Line: lineOf(t, content, "[quickstart]")},
+		{Href: "https://docs.siderolabs.com/omni/overview", Path: "omni/overview", Text: "Omni docs", Line: lineOf(t, content, "[Omni docs]")},
+		{Href: "/talos/v1.11/networking/discovery", Path: "talos/v1.11/networking/discovery", Line: lineOf(t, content, "<Card")},
+		// Reference definitions, resolved at the definition
+		{Href: "/talos/v1.11/install", Path: "talos/v1.11/install", Line: lineOf(t, content, "[install]: ")},
+	}
+
+	links := parseLinks(content, linksPagePath+".mdx", defaultPublicURL)
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("parseLinks() =\n%+v\nwant\n%+v", links, want)
+	}
+
+	// Without a template mapping page paths, absolute links are external
+	links = parseLinks(content, linksPagePath, "https://docs.example.com/{version}/{page}")
+	for _, link := range links {
+		if link.Href == want[3].Href || link.Href == want[4].Href {
+			t.Errorf("absolute link %s parsed without a {path} template", link.Href)
+		}
+	}
+	if len(links) != len(want)-2 {
+		t.Errorf("parsed %d links without a {path} template, want %d", len(links), len(want)-2)
+	}
+}
+
+func TestInternalLink(t *testing.T) {
+	tests := []struct {
+		href       string
+		wantPath   string
+		wantAnchor string
+		internal   bool
+	}{
+		{href: "kubespan", wantPath: "talos/v1.11/networking/kubespan", internal: true},
+		{href: "./kubespan.md", wantPath: "talos/v1.11/networking/kubespan", internal: true},
+		{href: "../../v1.10/networking/vip/", wantPath: "talos/v1.10/networking/vip", internal: true},
+		{href: "/talos/v1.11/networking/kubespan#setup", wantPath: "talos/v1.11/networking/kubespan", wantAnchor: "setup", internal: true},
+		{href: "HTTPS://DOCS.SIDEROLABS.COM/talos/v1.11/networking/kubespan", wantPath: "talos/v1.11/networking/kubespan", internal: true},
+		{href: "#caveats"},
+		{href: "https://github.com/siderolabs/talos"},
+		{href: "mailto:security@siderolabs.com"},
+		{href: "/images/vip.png"},
+		{href: "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			link, internal := internalLink(tt.href, linksPagePath, defaultPublicURL)
+			if internal != tt.internal {
+				t.Fatalf("internalLink() internal = %v, want %v (path %q)", internal, tt.internal, link.Path)
+			}
+			if internal && (link.Path != tt.wantPath || link.Anchor != tt.wantAnchor) {
+				t.Errorf("internalLink() = %q#%q, want %q#%q", link.Path, link.Anchor, tt.wantPath, tt.wantAnchor)
+			}
+		})
+	}
+}
+
+func TestResolveLinksAndBacklinks(t *testing.T) {
+	// The Omni overview isn't indexed but exists in the checkout
+	checkout := t.TempDir()
+	omni := filepath.Join(checkout, "public", "omni")
+	if err := os.MkdirAll(omni, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(omni, "overview.mdx"), []byte("# Omni\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := &DocumentationFetcher{localPath: checkout}
+
+	page := func(pagePath string, links ...DocumentLink) *Document {
+		return &Document{ID: documentID("v1.11", pagePath), Path: pagePath, Version: "v1.11", Links: links}
+	}
+	link := func(target string) DocumentLink {
+		return DocumentLink{Href: "/" + target, Path: target, Line: 1}
+	}
+	vip := page(linksPagePath,
+		link("talos/v1.11/networking/kubespan"),
+		link("talos/v1.11/networking/kubespan"),
+		link("talos/v1.11/install"),
+		link("omni/overview"),
+		link("talos/v1.11/networking/missing"),
+		link(linksPagePath),
+	)
+	kubespan := page("talos/v1.11/networking/kubespan", link(linksPagePath))
+	install := page("talos/v1.11/getting-started/install", link("talos/v1.11/networking/kubespan"))
+	documents := []*Document{vip, kubespan, install}
+
+	report := newExtractionReport()
+	redirects := []Redirect{{Source: "/talos/v1.11/install", Destination: "/talos/v1.11/getting-started/install"}}
+	fetcher.resolveLinks(documents, redirects, report)
+
+	wantTargets := []struct {
+		target string
+		broken bool
+	}{
+		{target: kubespan.ID},
+		{target: kubespan.ID},
+		// Followed through the redirect
+		{target: install.ID},
+		// Another product's page: neither indexed nor broken
+		{},
+		{broken: true},
+		{target: vip.ID},
+	}
+	for i, want := range wantTargets {
+		if got := vip.Links[i]; got.Target != want.target || got.Broken != want.broken {
+			t.Errorf("link to %s resolved to %q (broken %v), want %q (broken %v)", got.Href, got.Target, got.Broken, want.target, want.broken)
+		}
+	}
+	if len(report.BrokenLinks) != 1 || report.BrokenLinks[0].Location != "/talos/v1.11/networking/missing" {
+		t.Errorf("broken links reported as %+v", report.BrokenLinks)
+	}
+
+	backlinks := buildBacklinks(documentsByID(documents))
+	want := map[string][]string{
+		// Counted once per linking page, however often it links
+		kubespan.ID: {install.ID, vip.ID},
+		install.ID:  {vip.ID},
+		// The self link doesn't count
+		vip.ID: {kubespan.ID},
+	}
+	if !reflect.DeepEqual(backlinks, want) {
+		t.Errorf("buildBacklinks() = %v, want %v", backlinks, want)
+	}
+
+	if boost := linkRankBoost(0); boost != 1 {
+		t.Errorf("linkRankBoost(0) = %v, want 1", boost)
+	}
+	if linkRankBoost(len(backlinks[kubespan.ID])) <= linkRankBoost(len(backlinks[install.ID])) {
+		t.Error("a page with more backlinks isn't boosted more")
+	}
+}
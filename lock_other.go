@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockIndex opens the index lock file without locking it: flock isn't
+// available here, so keeping a single writer per index directory is up to
+// the operator.
+func lockIndex(indexPath string) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(indexPath, indexLockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index lock: %w", err)
+	}
+	return file, nil
+}
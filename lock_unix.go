@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockIndex takes the exclusive writer lock on an index directory. The lock
+// is an flock on indexLockFile, so the kernel drops it when the holder
+// exits, crashed or not. It fails straight away if another process holds it.
+func lockIndex(indexPath string) (*os.File, error) {
+	lockPath := filepath.Join(indexPath, indexLockFile)
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index lock: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := os.ReadFile(lockPath)
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("index %s is in use by another process (pid %s)", indexPath, strings.TrimSpace(string(holder)))
+		}
+		return nil, fmt.Errorf("failed to lock index: %w", err)
+	}
+
+	// Record the holder for the error above; the lock itself is the flock
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return file, nil
+}
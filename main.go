@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+
+	"gopkg.in/yaml.v3"
 )
 
 func loadConfig() (*Config, error) {
@@ -13,9 +17,19 @@ func loadConfig() (*Config, error) {
 	// Set defaults
 	config.Server.Name = "talos-docs-mcp"
 	config.Server.Version = "1.0.0"
+	config.Server.Transport = transportStdio
+	config.Server.Address = ":8080"
 	
 	config.Repository.URL = "https://github.com/siderolabs/docs"
 	config.Repository.Branch = "main"
+	config.Repository.HistoryDepth = 200
+	config.Repository.PublicURL = defaultPublicURL
+	
+	config.Limits.PerClient = RateLimit{RequestsPerMinute: 120, Burst: 30}
+	config.Limits.Tools = map[string]RateLimit{
+		"sync_documentation":    {RequestsPerMinute: 2, Burst: 1},
+		"get_index_diagnostics": {RequestsPerMinute: 6, Burst: 2},
+	}
 	
 	config.Sync.Mode = "hybrid"
 	config.Sync.Webhook.Secret = ""
@@ -28,6 +42,8 @@ func loadConfig() (*Config, error) {
 	config.Search.IndexPath = "./data/search_index"
 	config.Search.MaxResults = 20
 	config.Search.SnippetLength = 300
+	config.Search.RetainedGenerations = 3
+	config.Search.MaxDocumentDrop = 20
 	
 	config.Cache.TTL = "24h"
 	config.Cache.MaxSize = "1GB"
@@ -38,11 +54,18 @@ func loadConfig() (*Config, error) {
 	config.Monitoring.MetricsEnabled = true
 	config.Monitoring.AlertsEnabled = true
 
-	// Try to load from config file if it exists
+	// Load the config file if it exists; its values override the defaults
 	configPath := "config.yaml"
-	if _, err := os.Stat(configPath); err == nil {
-		// For now, just use defaults since we don't have YAML parsing
-		log.Printf("Found config file at %s, using defaults for now", configPath)
+	if path := os.Getenv("TALOS_MCP_CONFIG"); path != "" {
+		configPath = path
+	}
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+		}
+		log.Printf("Loaded config file %s", configPath)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
 	// Override with environment variables
@@ -52,21 +75,63 @@ func loadConfig() (*Config, error) {
 	if version := os.Getenv("TALOS_MCP_VERSION"); version != "" {
 		config.Server.Version = version
 	}
+	if transport := os.Getenv("TALOS_MCP_TRANSPORT"); transport != "" {
+		config.Server.Transport = transport
+	}
+	if address := os.Getenv("TALOS_MCP_ADDRESS"); address != "" {
+		config.Server.Address = address
+	}
 	if repoURL := os.Getenv("TALOS_MCP_REPO_URL"); repoURL != "" {
 		config.Repository.URL = repoURL
 	}
 	if branch := os.Getenv("TALOS_MCP_BRANCH"); branch != "" {
 		config.Repository.Branch = branch
 	}
+	if publicURL := os.Getenv("TALOS_MCP_PUBLIC_URL"); publicURL != "" {
+		config.Repository.PublicURL = publicURL
+	}
+	if historyDepth := os.Getenv("TALOS_MCP_HISTORY_DEPTH"); historyDepth != "" {
+		depth, err := strconv.Atoi(historyDepth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TALOS_MCP_HISTORY_DEPTH %q: %w", historyDepth, err)
+		}
+		config.Repository.HistoryDepth = depth
+	}
 	if indexPath := os.Getenv("TALOS_MCP_INDEX_PATH"); indexPath != "" {
 		config.Search.IndexPath = indexPath
 	}
+	if retained := os.Getenv("TALOS_MCP_RETAINED_GENERATIONS"); retained != "" {
+		count, err := strconv.Atoi(retained)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TALOS_MCP_RETAINED_GENERATIONS %q: %w", retained, err)
+		}
+		config.Search.RetainedGenerations = count
+	}
 	if webhookSecret := os.Getenv("TALOS_MCP_WEBHOOK_SECRET"); webhookSecret != "" {
 		config.Sync.Webhook.Secret = webhookSecret
 	}
 	if logLevel := os.Getenv("TALOS_MCP_LOG_LEVEL"); logLevel != "" {
 		config.Logging.Level = logLevel
 	}
+	if keysFile := os.Getenv("TALOS_MCP_KEYS_FILE"); keysFile != "" {
+		config.Auth.KeysFile = keysFile
+	}
+	if allow := os.Getenv("TALOS_MCP_ALLOW_UNAUTHENTICATED"); allow != "" {
+		allowed, err := strconv.ParseBool(allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TALOS_MCP_ALLOW_UNAUTHENTICATED %q: %w", allow, err)
+		}
+		config.Auth.AllowUnauthenticated = allowed
+	}
+
+	if err := validateTransport(config.Server.Transport); err != nil {
+		return nil, err
+	}
+	if config.Repository.PublicURL != "" {
+		if err := validatePublicURL(config.Repository.PublicURL); err != nil {
+			return nil, err
+		}
+	}
 
 	return config, nil
 }
@@ -87,6 +152,14 @@ func main() {
 	// Setup logging
 	setupLogging(config)
 
+	// Subcommands run to completion instead of starting the server
+	if len(os.Args) > 1 {
+		if err := runCommand(config, os.Args[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	log.Printf("Starting %s v%s", config.Server.Name, config.Server.Version)
 
 	// Create MCP server
@@ -101,17 +174,26 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start server in goroutine
+	// Start server in goroutine; it returns when the transport fails (e.g.
+	// the port is taken) or stdin closes
+	serveErr := make(chan error, 1)
 	go func() {
-		if err := server.Start(); err != nil {
-			log.Printf("Server error: %v", err)
-		}
+		serveErr <- server.Start()
 	}()
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Println("Shutdown signal received, stopping server...")
-	
+	// Wait for a shutdown signal or the transport to stop
+	select {
+	case <-sigChan:
+		log.Println("Shutdown signal received, stopping server...")
+	case err := <-serveErr:
+		server.Stop()
+		if err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+		log.Println("Transport closed, server stopped")
+		return
+	}
+
 	// Cleanup
 	server.Stop()
 	log.Println("Server stopped")
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// manifestFile records the current generation and the generations
+	// published before it
+	manifestFile = "manifest.json"
+
+	// legacyCurrentFile is the plain-text pointer used before the manifest
+	legacyCurrentFile = "CURRENT"
+)
+
+// indexManifest is the on-disk record of which generations were published.
+// Only generations that passed their checks and were published are listed,
+// so recovery never picks up a half-written or rejected one.
+type indexManifest struct {
+	Current     string   `json:"current"`
+	Generations []string `json:"generations"` // published generations, most recently published first
+	// DocumentSchema is the current generation's document schema version
+	DocumentSchema int       `json:"document_schema"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// readManifest reads the manifest. Index directories from before the
+// manifest get one built from CURRENT, with every generation on disk
+// treated as published.
+func (se *SearchEngine) readManifest() (*indexManifest, error) {
+	data, err := os.ReadFile(filepath.Join(se.indexPath, manifestFile))
+	if os.IsNotExist(err) {
+		return se.legacyManifest()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestFile, err)
+	}
+
+	var manifest indexManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", manifestFile, err)
+	}
+	return &manifest, nil
+}
+
+func (se *SearchEngine) legacyManifest() (*indexManifest, error) {
+	manifest := &indexManifest{Generations: se.listGenerations()}
+
+	data, err := os.ReadFile(filepath.Join(se.indexPath, legacyCurrentFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", legacyCurrentFile, err)
+	}
+	manifest.Current = strings.TrimSpace(string(data))
+	return manifest, nil
+}
+
+// writeManifest records current, whose documents follow schema, as the
+// current generation, followed by the previously published generations
+// still retained.
+func (se *SearchEngine) writeManifest(current string, schema int, previous []string) error {
+	manifest := indexManifest{
+		Current:        current,
+		Generations:    []string{current},
+		DocumentSchema: schema,
+		UpdatedAt:      time.Now().UTC(),
+	}
+	for _, id := range previous {
+		if id != current {
+			manifest.Generations = append(manifest.Generations, id)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", manifestFile, err)
+	}
+	if err := writeFileSynced(filepath.Join(se.indexPath, manifestFile), data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifestFile, err)
+	}
+
+	// The manifest supersedes CURRENT; a stale one would only mislead
+	os.Remove(filepath.Join(se.indexPath, legacyCurrentFile))
+	return nil
+}
+
+// writeFileSynced replaces path with data so that after a crash it holds
+// either the old or the new contents in full: the data is written to a
+// temporary file and flushed, renamed over path, and the rename is flushed
+// by syncing the directory.
+func writeFileSynced(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes a directory's entries, making renames into it durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+// syncTree flushes every file and directory under root, so a directory
+// renamed into place afterwards is complete on disk.
+func syncTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return file.Sync()
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+)
+
+// markdownSection is one heading of a page and the lines up to the next
+// heading of any level. Text before the first heading (including
+// frontmatter) forms a section with an empty heading.
+type markdownSection struct {
+	Heading   string // heading text without the leading #s
+	Level     int    // 1 for #, 2 for ## and so on; 0 before the first heading
+	Trail     string // enclosing headings and this one, joined with " > "
+	Body      string // the heading line and everything up to the next heading
+	StartLine int    // 1-based line of the heading in the page
+	EndLine   int    // last line of the section
+}
+
+// splitSections splits page content at its headings. Lines inside code
+// fences and frontmatter are never treated as headings, so shell comments
+// in examples don't start sections.
+func splitSections(content string) []markdownSection {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var sections []markdownSection
+	current := markdownSection{StartLine: 1}
+	var body strings.Builder
+	var trail []string // heading text per level, index 0 for level 1
+	fence := ""
+	inFrontmatter := len(lines) > 0 && strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff")) == "---"
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case inFrontmatter:
+			if i > 0 && trimmed == "---" {
+				inFrontmatter = false
+			}
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if level, heading := parseHeading(line); level > 0 {
+				sections = appendSection(sections, current, body.String(), i)
+				body.Reset()
+
+				for len(trail) < level {
+					trail = append(trail, "")
+				}
+				trail = append(trail[:level-1], heading)
+				current = markdownSection{
+					Heading:   heading,
+					Level:     level,
+					Trail:     joinTrail(trail),
+					StartLine: i + 1,
+				}
+			}
+		}
+
+		body.WriteString(line)
+	}
+
+	return appendSection(sections, current, body.String(), len(lines))
+}
+
+// appendSection completes section with its body, dropping a blank intro.
+func appendSection(sections []markdownSection, section markdownSection, body string, endLine int) []markdownSection {
+	if section.Level == 0 && strings.TrimSpace(body) == "" {
+		return sections
+	}
+	section.Body = body
+	section.EndLine = endLine
+	return append(sections, section)
+}
+
+// parseHeading recognises ATX headings ("## Title"). It returns level 0 for
+// anything else.
+func parseHeading(line string) (int, string) {
+	// Headings indented four or more spaces are code blocks
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return 0, ""
+	}
+	trimmed := strings.TrimSpace(line)
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	if level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t' {
+		return 0, ""
+	}
+
+	heading := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+	return level, heading
+}
+
+// joinTrail joins the non-empty headings of a trail, skipping levels the
+// page jumped over.
+func joinTrail(trail []string) string {
+	parts := make([]string, 0, len(trail))
+	for _, heading := range trail {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// codeBlocks returns the contents of the code blocks in text, only those
+// tagged with language unless it is empty.
+func codeBlocks(text, language string) []string {
+	var blocks []string
+	var block []string
+	inFence, match := false, false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inFence && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "{{< highlight")):
+			inFence = true
+			match = language == "" || strings.Contains(trimmed, language)
+			block = nil
+		case inFence && (trimmed == "```" || strings.HasPrefix(trimmed, "{{< /highlight")):
+			inFence = false
+			if match && len(block) > 0 {
+				blocks = append(blocks, strings.Join(block, "\n"))
+			}
+		case inFence:
+			block = append(block, line)
+		}
+	}
+	return blocks
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -8,13 +9,40 @@ type Document struct {
 	ID          string                 `json:"id"`
 	Title       string                 `json:"title"`
 	Content     string                 `json:"content"`
+	ContentHash string                 `json:"content_hash,omitempty"`
 	Path        string                 `json:"path"`
 	Version     string                 `json:"version"`
 	Section     string                 `json:"section"`
 	Platform    string                 `json:"platform,omitempty"`
 	Tags        []string               `json:"tags"`
 	LastUpdated time.Time              `json:"last_updated"`
+	LastCommit  *CommitInfo            `json:"last_commit,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata"`
+
+	// URL is the page on the public docs site, from the repository's
+	// public URL template
+	URL string `json:"url,omitempty"`
+
+	// Navigation is the docs.json group trail listing the page, outermost
+	// first, and NavigationOrder its position within the version's
+	// navigation
+	Navigation      []string `json:"navigation,omitempty"`
+	NavigationOrder int      `json:"navigation_order,omitempty"`
+
+	// ReleaseNotes holds the changes parsed from what's-new and release
+	// notes pages
+	ReleaseNotes []ReleaseNote `json:"release_notes,omitempty"`
+
+	// ConfigFields holds the fields parsed from machine configuration
+	// reference pages
+	ConfigFields []ConfigField `json:"config_fields,omitempty"`
+
+	// TalosctlCommands holds the commands parsed from the talosctl CLI
+	// reference
+	TalosctlCommands []TalosctlCommand `json:"talosctl_commands,omitempty"`
+
+	// Links holds the page's links to other documentation pages
+	Links []DocumentLink `json:"links,omitempty"`
 }
 
 type SearchResult struct {
@@ -22,6 +50,10 @@ type SearchResult struct {
 	Score    float64   `json:"score"`
 	Snippet  string    `json:"snippet"`
 	Context  string    `json:"context"`
+	// Backlinks counts the pages linking to this one, which lifts its score
+	Backlinks int `json:"backlinks,omitempty"`
+	// NextCursor continues a page cut to one chunk, via get_talos_document
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type DocsNavigation struct {
@@ -29,6 +61,14 @@ type DocsNavigation struct {
 	Theme    string            `json:"theme"`
 	Name     string            `json:"name"`
 	Navigation NavigationTabs  `json:"navigation"`
+	Redirects  []Redirect      `json:"redirects,omitempty"`
+}
+
+// Redirect is a docs.json redirect between public site paths.
+type Redirect struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Permanent   bool   `json:"permanent,omitempty"`
 }
 
 type NavigationTabs struct {
@@ -47,19 +87,41 @@ type Version struct {
 }
 
 type Group struct {
-	Group  string      `json:"group"`
-	Pages  []PageItem  `json:"pages"`
+	Group    string     `json:"group"`
+	Icon     string     `json:"icon,omitempty"`
+	Expanded bool       `json:"expanded,omitempty"`
+	Root     string     `json:"root,omitempty"`
+	Tag      string     `json:"tag,omitempty"`
+	Pages    []PageItem `json:"pages"`
 }
 
-type PageItem interface{}
+type PageItemKind string
+
+const (
+	PageItemPage    PageItemKind = "page"
+	PageItemGroup   PageItemKind = "group"
+	PageItemLink    PageItemKind = "link"
+	PageItemUnknown PageItemKind = "unknown"
+)
 
-type Page struct {
-	Page string `json:"page"`
+// PageItem is one entry of a group's pages list. docs.json mixes bare page
+// paths, nested groups and external links in the same array, so decoding
+// sorts each entry into a Kind and keeps the raw JSON of anything it doesn't
+// recognise.
+type PageItem struct {
+	Kind  PageItemKind
+	Page  string
+	Group *Group
+	Link  *ExternalLink
+	Raw   json.RawMessage
+	// Problem explains why an item decoded as PageItemUnknown
+	Problem string
 }
 
-type NestedGroup struct {
-	Group string     `json:"group"`
-	Pages []PageItem `json:"pages"`
+type ExternalLink struct {
+	Title string `json:"title,omitempty"`
+	Href  string `json:"href"`
+	Icon  string `json:"icon,omitempty"`
 }
 
 type SyncMode int
@@ -80,13 +142,22 @@ type WebhookEvent struct {
 
 type Config struct {
 	Server struct {
-		Name    string `yaml:"name"`
-		Version string `yaml:"version"`
+		Name      string `yaml:"name"`
+		Version   string `yaml:"version"`
+		Transport string `yaml:"transport"` // stdio, sse or http
+		Address   string `yaml:"address"`   // listen address for sse and http
 	} `yaml:"server"`
 	
+	Auth AuthConfig `yaml:"auth"`
+
+	Limits LimitsConfig `yaml:"limits"`
+
 	Repository struct {
-		URL    string `yaml:"url"`
-		Branch string `yaml:"branch"`
+		URL          string `yaml:"url"`
+		Branch       string `yaml:"branch"`
+		HistoryDepth int    `yaml:"history_depth"`
+		// PublicURL maps pages to the public docs site; see publicurl.go
+		PublicURL string `yaml:"public_url"`
 	} `yaml:"repository"`
 	
 	Sync struct {
@@ -106,9 +177,11 @@ type Config struct {
 	} `yaml:"sync"`
 	
 	Search struct {
-		IndexPath      string `yaml:"index_path"`
-		MaxResults     int    `yaml:"max_results"`
-		SnippetLength  int    `yaml:"snippet_length"`
+		IndexPath           string  `yaml:"index_path"`
+		MaxResults          int     `yaml:"max_results"`
+		SnippetLength       int     `yaml:"snippet_length"`
+		RetainedGenerations int     `yaml:"retained_generations"`
+		MaxDocumentDrop     float64 `yaml:"max_document_drop"` // percent
 	} `yaml:"search"`
 	
 	Cache struct {
@@ -125,4 +198,33 @@ type Config struct {
 		MetricsEnabled bool `yaml:"metrics_enabled"`
 		AlertsEnabled  bool `yaml:"alerts_enabled"`
 	} `yaml:"monitoring"`
-}
\ No newline at end of file
+}
+
+// AuthConfig holds the API keys accepted by the HTTP transports. Keys can be
+// listed inline or kept in a separate secrets file with the same keys list.
+type AuthConfig struct {
+	Keys     []APIKey `yaml:"keys"`
+	KeysFile string   `yaml:"keys_file"`
+	// AllowUnauthenticated lets HTTP transports start without any keys
+	AllowUnauthenticated bool `yaml:"allow_unauthenticated"`
+}
+
+// APIKey is a static key or bearer token and the scopes it grants.
+type APIKey struct {
+	Name   string   `yaml:"name"`
+	Key    string   `yaml:"key"`
+	Scopes []string `yaml:"scopes"`
+}
+
+// LimitsConfig rate limits tool calls per client, across all tools and per
+// tool. A zero requests_per_minute disables a limit.
+type LimitsConfig struct {
+	PerClient RateLimit            `yaml:"per_client"`
+	Tools     map[string]RateLimit `yaml:"tools"`
+}
+
+// RateLimit is a token bucket: requests_per_minute sustained, burst at once.
+type RateLimit struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst"`
+}
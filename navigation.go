@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// talosTab is the docs.json tab holding the Talos documentation, the only
+// one extracted and indexed.
+const talosTab = "Talos"
+
+// UnmarshalJSON decodes a pages entry. It never fails on shapes it doesn't
+// know: those become PageItemUnknown with the raw JSON kept, so a new
+// Mintlify feature surfaces as a navigation warning instead of aborting the
+// whole docs.json decode.
+func (p *PageItem) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	*p = PageItem{Raw: append(json.RawMessage(nil), data...)}
+
+	if len(data) == 0 {
+		p.markUnknown("empty entry")
+		return nil
+	}
+
+	switch data[0] {
+	case '"':
+		var page string
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		p.Kind = PageItemPage
+		p.Page = page
+		return nil
+	case '{':
+		// handled below
+	default:
+		p.markUnknown("entry is neither a page path nor an object")
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	switch {
+	case fields["group"] != nil:
+		var group Group
+		if err := json.Unmarshal(data, &group); err != nil {
+			p.markUnknown(fmt.Sprintf("invalid group: %v", err))
+			return nil
+		}
+		p.Kind = PageItemGroup
+		p.Group = &group
+	case fields["page"] != nil:
+		var page struct {
+			Page string `json:"page"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil || page.Page == "" {
+			p.markUnknown("page object without a page path")
+			return nil
+		}
+		p.Kind = PageItemPage
+		p.Page = page.Page
+	case fields["href"] != nil || fields["url"] != nil:
+		var link struct {
+			ExternalLink
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(data, &link); err != nil {
+			p.markUnknown(fmt.Sprintf("invalid link: %v", err))
+			return nil
+		}
+		if link.Href == "" {
+			link.Href = link.URL
+		}
+		p.Kind = PageItemLink
+		p.Link = &link.ExternalLink
+	default:
+		p.markUnknown("object has no group, page or href key")
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the item back in docs.json form.
+func (p PageItem) MarshalJSON() ([]byte, error) {
+	switch p.Kind {
+	case PageItemPage:
+		return json.Marshal(p.Page)
+	case PageItemGroup:
+		return json.Marshal(p.Group)
+	case PageItemLink:
+		return json.Marshal(p.Link)
+	default:
+		if len(p.Raw) == 0 {
+			return []byte("null"), nil
+		}
+		return p.Raw, nil
+	}
+}
+
+func (p *PageItem) markUnknown(problem string) {
+	p.Kind = PageItemUnknown
+	p.Problem = problem
+}
+
+// Warnings lists every navigation entry that couldn't be decoded, with its
+// location in the tab/version/group hierarchy.
+func (nav *DocsNavigation) Warnings() []string {
+	var warnings []string
+	for _, tab := range nav.Navigation.Tabs {
+		for _, version := range tab.Versions {
+			for _, group := range version.Groups {
+				location := fmt.Sprintf("%s/%s/%s", tab.Tab, version.Version, group.Group)
+				warnings = appendNavigationWarnings(warnings, location, group.Pages)
+			}
+		}
+	}
+	return warnings
+}
+
+func appendNavigationWarnings(warnings []string, location string, pages []PageItem) []string {
+	for _, page := range pages {
+		switch page.Kind {
+		case PageItemUnknown:
+			raw := page.Raw
+			var compact bytes.Buffer
+			if json.Compact(&compact, raw) == nil {
+				raw = compact.Bytes()
+			}
+			warnings = append(warnings, fmt.Sprintf("%s: skipped unrecognised navigation entry %s (%s)", location, raw, page.Problem))
+		case PageItemGroup:
+			warnings = appendNavigationWarnings(warnings, location+"/"+page.Group.Group, page.Group.Pages)
+		}
+	}
+	return warnings
+}
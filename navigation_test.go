@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPageItemUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantKind  PageItemKind
+		wantPage  string
+		wantGroup string
+		wantHref  string
+	}{
+		{name: "page path", raw: `"talos/v1.11/networking/vip"`, wantKind: PageItemPage, wantPage: "talos/v1.11/networking/vip"},
+		{name: "page object", raw: `{"page": "talos/v1.11/networking/vip", "title": "VIP"}`, wantKind: PageItemPage, wantPage: "talos/v1.11/networking/vip"},
+		{name: "group", raw: `{"group": "Networking", "pages": ["talos/v1.11/networking/vip"]}`, wantKind: PageItemGroup, wantGroup: "Networking"},
+		{name: "href link", raw: `{"title": "GitHub", "href": "https://github.com/siderolabs/talos"}`, wantKind: PageItemLink, wantHref: "https://github.com/siderolabs/talos"},
+		{name: "url link", raw: `{"title": "Factory", "url": "https://factory.talos.dev"}`, wantKind: PageItemLink, wantHref: "https://factory.talos.dev"},
+		{name: "number", raw: `42`, wantKind: PageItemUnknown},
+		{name: "null", raw: `null`, wantKind: PageItemUnknown},
+		{name: "array", raw: `["a", "b"]`, wantKind: PageItemUnknown},
+		{name: "object without known keys", raw: `{"icon": "cloud"}`, wantKind: PageItemUnknown},
+		{name: "page object without a path", raw: `{"page": ""}`, wantKind: PageItemUnknown},
+		{name: "group with invalid pages", raw: `{"group": "Broken", "pages": "not-a-list"}`, wantKind: PageItemUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item PageItem
+			if err := json.Unmarshal([]byte(tt.raw), &item); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if item.Kind != tt.wantKind {
+				t.Fatalf("Kind = %q, want %q", item.Kind, tt.wantKind)
+			}
+
+			switch item.Kind {
+			case PageItemPage:
+				if item.Page != tt.wantPage {
+					t.Errorf("Page = %q, want %q", item.Page, tt.wantPage)
+				}
+			case PageItemGroup:
+				if item.Group.Group != tt.wantGroup {
+					t.Errorf("Group = %q, want %q", item.Group.Group, tt.wantGroup)
+				}
+			case PageItemLink:
+				if item.Link.Href != tt.wantHref {
+					t.Errorf("Href = %q, want %q", item.Link.Href, tt.wantHref)
+				}
+			case PageItemUnknown:
+				if item.Problem == "" {
+					t.Error("unknown item has no Problem")
+				}
+				if string(item.Raw) != tt.raw {
+					t.Errorf("Raw = %s, want %s", item.Raw, tt.raw)
+				}
+			}
+		})
+	}
+}
+
+func TestDocsNavigationFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/docs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nav DocsNavigation
+	if err := json.Unmarshal(data, &nav); err != nil {
+		t.Fatalf("failed to decode docs.json fixture: %v", err)
+	}
+
+	kinds := make(map[PageItemKind]int)
+	var pages []string
+	var walk func(items []PageItem)
+	walk = func(items []PageItem) {
+		for _, item := range items {
+			kinds[item.Kind]++
+			switch item.Kind {
+			case PageItemPage:
+				pages = append(pages, item.Page)
+			case PageItemGroup:
+				walk(item.Group.Pages)
+			}
+		}
+	}
+	for _, tab := range nav.Navigation.Tabs {
+		for _, version := range tab.Versions {
+			for _, group := range version.Groups {
+				walk(group.Pages)
+			}
+		}
+	}
+
+	wantKinds := map[PageItemKind]int{PageItemPage: 5, PageItemGroup: 1, PageItemLink: 2, PageItemUnknown: 5}
+	for kind, want := range wantKinds {
+		if kinds[kind] != want {
+			t.Errorf("%d %s items, want %d", kinds[kind], kind, want)
+		}
+	}
+	if want := "talos/v1.11/platforms/metal/pxe"; !strings.Contains(strings.Join(pages, " "), want) {
+		t.Errorf("nested group page %s not decoded, got %v", want, pages)
+	}
+	if len(nav.Redirects) != 1 || !nav.Redirects[0].Permanent {
+		t.Errorf("redirects decoded as %+v", nav.Redirects)
+	}
+
+	warnings := nav.Warnings()
+	if len(warnings) != kinds[PageItemUnknown] {
+		t.Errorf("%d warnings for %d unknown items: %v", len(warnings), kinds[PageItemUnknown], warnings)
+	}
+	nested := "Talos/v1.11/Platforms/Bare Metal: skipped unrecognised navigation entry 42"
+	found := false
+	for _, warning := range warnings {
+		if strings.HasPrefix(warning, nested) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no warning locating the entry in its nested group, got %v", warnings)
+	}
+
+	// Re-encoding keeps every entry, unknown ones as they were
+	encoded, err := json.Marshal(nav)
+	if err != nil {
+		t.Fatalf("failed to re-encode navigation: %v", err)
+	}
+	var again DocsNavigation
+	if err := json.Unmarshal(encoded, &again); err != nil {
+		t.Fatalf("failed to decode re-encoded navigation: %v", err)
+	}
+	if got := again.Warnings(); strings.Join(got, "\n") != strings.Join(warnings, "\n") {
+		t.Errorf("warnings changed after a round trip:\n%v\nwant\n%v", got, warnings)
+	}
+}
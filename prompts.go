@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// promptDocumentLimit caps how many pages a prompt embeds, keeping the
+	// context it pulls in focused. Each search returns at most this many.
+	promptDocumentLimit = maxSearchResults
+
+	// upgradePromptPagesPerVersion is how many pages the upgrade prompt
+	// embeds for each version along the upgrade path.
+	upgradePromptPagesPerVersion = 2
+)
+
+func (s *TalosDocMCPServer) registerPrompts() {
+	upgradePrompt := mcp.NewPrompt("plan_talos_upgrade",
+		mcp.WithPromptDescription("Plan a Talos upgrade between two versions, with the upgrade guide and release notes attached"),
+		mcp.WithArgument("from_version",
+			mcp.ArgumentDescription("Version the cluster runs today (e.g. v1.9)"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("to_version",
+			mcp.ArgumentDescription("Version to upgrade to (e.g. v1.11)"),
+			mcp.RequiredArgument(),
+		),
+	)
+
+	s.mcpServer.AddPrompt(upgradePrompt, s.handleUpgradePrompt)
+
+	bootstrapPrompt := mcp.NewPrompt("bootstrap_talos_cluster",
+		mcp.WithPromptDescription("Bootstrap a new Talos cluster on a platform, with the platform install guide attached"),
+		mcp.WithArgument("platform",
+			mcp.ArgumentDescription("Cloud platform or hardware (e.g. aws, proxmox, rpi)"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("version",
+			mcp.ArgumentDescription("Talos version (default: latest)"),
+		),
+	)
+
+	s.mcpServer.AddPrompt(bootstrapPrompt, s.handleBootstrapPrompt)
+
+	troubleshootPrompt := mcp.NewPrompt("troubleshoot_node_not_joining",
+		mcp.WithPromptDescription("Troubleshoot a node that doesn't join the cluster, with the troubleshooting and discovery docs attached"),
+		mcp.WithArgument("version",
+			mcp.ArgumentDescription("Talos version (default: latest)"),
+		),
+		mcp.WithArgument("platform",
+			mcp.ArgumentDescription("Platform the node runs on"),
+		),
+		mcp.WithArgument("symptoms",
+			mcp.ArgumentDescription("What you observe: errors, talosctl output, node state"),
+		),
+	)
+
+	s.mcpServer.AddPrompt(troubleshootPrompt, s.handleTroubleshootPrompt)
+}
+
+func (s *TalosDocMCPServer) handleUpgradePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	fromVersion, err := s.resolveVersion(request.Params.Arguments["from_version"])
+	if err != nil {
+		return nil, fmt.Errorf("from_version: %w", err)
+	}
+	toVersion, err := s.resolveVersion(request.Params.Arguments["to_version"])
+	if err != nil {
+		return nil, fmt.Errorf("to_version: %w", err)
+	}
+
+	if compareVersions(fromVersion, toVersion) >= 0 {
+		return nil, fmt.Errorf("to_version %s must be newer than from_version %s", toVersion, fromVersion)
+	}
+
+	// Talos upgrades go through each minor release, so every version on
+	// the path gets its own upgrade guide and release notes
+	path := s.upgradeVersions(fromVersion, toVersion)
+	var documents []*Document
+	for _, version := range path {
+		found := s.searchDocuments(ctx, version, "", "upgrading talos what's new release notes breaking changes")
+		if len(found) > upgradePromptPagesPerVersion {
+			found = found[:upgradePromptPagesPerVersion]
+		}
+		documents = append(documents, found...)
+	}
+
+	instructions := fmt.Sprintf(`Plan an upgrade of a Talos Linux cluster from %s to %s, going through %s.
+
+Using the attached documentation for each version on that path:
+1. List the breaking changes and deprecations to handle before each step.
+2. Give the upgrade order (control plane vs workers) and the exact talosctl commands for each step.
+3. Call out required Kubernetes version changes and machine config changes.
+4. Describe how to verify each node and how to roll back.
+
+Cite the attached pages by their talos-docs:// URI.`, fromVersion, toVersion, strings.Join(append([]string{fromVersion}, path...), " -> "))
+
+	return promptResult(fmt.Sprintf("Upgrade plan from %s to %s", fromVersion, toVersion), instructions, documents), nil
+}
+
+func (s *TalosDocMCPServer) handleBootstrapPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	platform := strings.ToLower(strings.TrimSpace(request.Params.Arguments["platform"]))
+	if platform == "" {
+		return nil, fmt.Errorf("platform is required")
+	}
+	version, err := s.resolveVersion(request.Params.Arguments["version"])
+	if err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+
+	documents := s.searchDocuments(ctx, version, platform, platform+" installation")
+	documents = appendMissingDocuments(documents, s.searchDocuments(ctx, version, "", "getting started quickstart production notes"))
+
+	instructions := fmt.Sprintf(`Walk me through bootstrapping a new Talos Linux %s cluster on %s.
+
+Using the attached documentation:
+1. List the prerequisites (images, network, DNS/load balancer for the control plane endpoint).
+2. Show how to generate and patch machine configs with talosctl gen config.
+3. Give the steps to boot the nodes, apply configs and bootstrap etcd.
+4. Show how to retrieve the kubeconfig and verify the cluster is healthy.
+
+Cite the attached pages by their talos-docs:// URI.`, version, platform)
+
+	return promptResult(fmt.Sprintf("Bootstrap a Talos %s cluster on %s", version, platform), instructions, documents), nil
+}
+
+func (s *TalosDocMCPServer) handleTroubleshootPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	version, err := s.resolveVersion(request.Params.Arguments["version"])
+	if err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+	platform := strings.ToLower(strings.TrimSpace(request.Params.Arguments["platform"]))
+	symptoms := strings.TrimSpace(request.Params.Arguments["symptoms"])
+
+	documents := s.searchDocuments(ctx, version, "", strings.TrimSpace("troubleshooting node not joining cluster discovery "+symptoms))
+	if platform != "" {
+		documents = appendMissingDocuments(documents, s.searchDocuments(ctx, version, platform, platform))
+	}
+
+	var instructions strings.Builder
+	fmt.Fprintf(&instructions, "A node running Talos Linux %s is not joining the cluster", version)
+	if platform != "" {
+		fmt.Fprintf(&instructions, " on %s", platform)
+	}
+	instructions.WriteString(".\n\n")
+	if symptoms != "" {
+		fmt.Fprintf(&instructions, "Observed symptoms:\n%s\n\n", symptoms)
+	}
+	instructions.WriteString(`Using the attached documentation, diagnose the problem step by step:
+1. Check the node's network, control plane endpoint and certificates.
+2. Check discovery and the cluster membership the node sees.
+3. Give the talosctl commands (dmesg, logs, get members, health) to run at each step and what to look for.
+4. Suggest the most likely fixes given the symptoms.
+
+Cite the attached pages by their talos-docs:// URI.`)
+
+	return promptResult(fmt.Sprintf("Troubleshoot a Talos %s node not joining", version), instructions.String(), documents), nil
+}
+
+// searchDocuments ranks the pages of a version for query with the search
+// engine, only among pages about platform when one is given. A platform the
+// index doesn't tag pages with falls back to pages whose path mentions it.
+// Ranking is stable for a given index, so every user of a prompt gets the
+// same context. The full pages are returned rather than the search previews.
+func (s *TalosDocMCPServer) searchDocuments(ctx context.Context, version, platform, query string) []*Document {
+	documents := s.rankDocuments(ctx, &SearchRequest{
+		Query:    query,
+		Version:  version,
+		Platform: platform,
+		Limit:    promptDocumentLimit,
+	})
+	if len(documents) > 0 || platform == "" {
+		return documents
+	}
+
+	var mentioned []*Document
+	for _, doc := range s.rankDocuments(ctx, &SearchRequest{Query: query, Version: version, Limit: promptDocumentLimit}) {
+		if strings.Contains(strings.ToLower(doc.Path), platform) {
+			mentioned = append(mentioned, doc)
+		}
+	}
+	return mentioned
+}
+
+func (s *TalosDocMCPServer) rankDocuments(ctx context.Context, req *SearchRequest) []*Document {
+	response, err := s.searchEngine.Search(ctx, req)
+	if err != nil {
+		log.Printf("Warning: prompt search for %q failed: %v", req.Query, err)
+		return nil
+	}
+
+	var documents []*Document
+	for _, result := range response.Results {
+		if doc, exists := s.searchEngine.GetDocument(result.Document.ID); exists {
+			documents = append(documents, doc)
+		}
+	}
+	return documents
+}
+
+func appendMissingDocuments(documents, more []*Document) []*Document {
+	seen := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		seen[doc.ID] = true
+	}
+	for _, doc := range more {
+		if len(documents) == promptDocumentLimit {
+			break
+		}
+		if !seen[doc.ID] {
+			seen[doc.ID] = true
+			documents = append(documents, doc)
+		}
+	}
+	return documents
+}
+
+// promptResult builds the instructions message followed by one embedded
+// resource message per page.
+func promptResult(description, instructions string, documents []*Document) *mcp.GetPromptResult {
+	if len(documents) == 0 {
+		instructions += "\n\nNo matching pages were found in the index; say so rather than guessing at details."
+	}
+
+	messages := []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions)),
+	}
+	for _, doc := range documents {
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(documentContents(doc)[0])))
+	}
+
+	return mcp.NewGetPromptResult(description, messages)
+}
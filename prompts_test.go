@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestBootstrapPromptPlatformPages(t *testing.T) {
+	// Pages about other topics mention aws installation more densely than
+	// the platform pages do, so an unfiltered search ranks them first
+	var documents []*Document
+	for i := 0; i < 8; i++ {
+		documents = append(documents, &Document{
+			ID:      fmt.Sprintf("v1.11/talos/v1.11/networking/page-%d", i),
+			Title:   fmt.Sprintf("Networking %d", i),
+			Content: "aws installation aws installation",
+			Path:    fmt.Sprintf("talos/v1.11/networking/page-%d", i),
+			Version: "v1.11",
+		})
+	}
+	documents = append(documents,
+		&Document{
+			ID:       "v1.11/talos/v1.11/platforms/aws",
+			Title:    "AWS",
+			Content:  "# AWS\n\nCreate the instances, then follow the installation steps. " + strings.Repeat("Configure the VPC and load balancer. ", 40),
+			Path:     "talos/v1.11/platforms/aws",
+			Version:  "v1.11",
+			Platform: "aws",
+		},
+		&Document{
+			ID:      "v1.11/talos/v1.11/platforms/metal/pxe",
+			Title:   "PXE",
+			Content: "# PXE\n\nBoot metal machines over the network for installation. " + strings.Repeat("Set up DHCP and TFTP. ", 40),
+			Path:    "talos/v1.11/platforms/metal/pxe",
+			Version: "v1.11",
+		},
+	)
+
+	searchEngine := openTestEngine(t, t.TempDir())
+	t.Cleanup(func() { searchEngine.Close() })
+	if err := searchEngine.IndexDocuments(documents, "abc", nil); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+	config := &Config{}
+	config.Server.Transport = transportStdio
+	s, err := newTalosDocMCPServer(config, nil, searchEngine)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{platform: "aws", want: "talos-docs://v1.11/talos/v1.11/platforms/aws"},
+		// No page is tagged with metal, so the page whose path mentions it is used
+		{platform: "metal", want: "talos-docs://v1.11/talos/v1.11/platforms/metal/pxe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			request := mcp.GetPromptRequest{}
+			request.Params.Arguments = map[string]string{"platform": tt.platform, "version": "v1.11"}
+			result, err := s.handleBootstrapPrompt(context.Background(), request)
+			if err != nil {
+				t.Fatalf("handleBootstrapPrompt() error = %v", err)
+			}
+
+			var uris []string
+			for _, message := range result.Messages {
+				if resource, ok := message.Content.(mcp.EmbeddedResource); ok {
+					uris = append(uris, resource.Resource.(mcp.TextResourceContents).URI)
+				}
+			}
+			if len(uris) == 0 || uris[0] != tt.want {
+				t.Errorf("embedded %v, want %s first", uris, tt.want)
+			}
+			if len(uris) > promptDocumentLimit {
+				t.Errorf("embedded %d pages, more than the limit of %d", len(uris), promptDocumentLimit)
+			}
+		})
+	}
+}
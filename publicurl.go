@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// defaultPublicURL maps pages to docs.siderolabs.com, which serves them
+	// at their page path
+	defaultPublicURL = "https://docs.siderolabs.com/{path}"
+
+	// maxRedirectHops bounds how many docs.json redirects are followed
+	maxRedirectHops = 10
+)
+
+var urlPlaceholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// publicURLPlaceholders are what a public URL template can refer to: the
+// page path (talos/v1.11/networking/vip), its version and the page path
+// without the version segment (talos/networking/vip).
+var publicURLPlaceholders = map[string]bool{"{path}": true, "{version}": true, "{page}": true}
+
+// validatePublicURL checks that a public URL template only uses known
+// placeholders and expands to an absolute URL.
+func validatePublicURL(template string) error {
+	for _, placeholder := range urlPlaceholderPattern.FindAllString(template, -1) {
+		if !publicURLPlaceholders[placeholder] {
+			return fmt.Errorf("invalid public URL template %q: unknown placeholder %s (expected {path}, {version} or {page})", template, placeholder)
+		}
+	}
+	parsed, err := url.Parse(expandPublicURL(template, "talos/v1.11/networking/vip", "v1.11"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid public URL template %q: must expand to an absolute URL", template)
+	}
+	return nil
+}
+
+// expandPublicURL fills in a public URL template for a page.
+func expandPublicURL(template, pagePath, version string) string {
+	pagePath = publicPagePath(pagePath)
+	return strings.NewReplacer(
+		"{path}", pagePath,
+		"{version}", version,
+		"{page}", crossVersionPath(pagePath, version),
+	).Replace(template)
+}
+
+// publicPagePath is the path the docs site serves a page at: index pages
+// are served at their directory.
+func publicPagePath(pagePath string) string {
+	pagePath = normalizePagePath(pagePath)
+	if pagePath == "index" {
+		return ""
+	}
+	return strings.TrimSuffix(pagePath, "/index")
+}
+
+// sectionAnchor is the anchor of a heading: its explicit {#id} if it has
+// one, otherwise the slug docs sites derive from its text.
+func sectionAnchor(heading string) string {
+	if match := headingAnchorPattern.FindStringSubmatch(heading); match != nil {
+		return match[1]
+	}
+	return headingAnchor(heading)
+}
+
+// sectionURL links to a section of doc's public page, or to the page
+// itself without a heading. Documents indexed before public URLs were
+// recorded have none.
+func sectionURL(doc *Document, heading string) string {
+	if doc.URL == "" || heading == "" {
+		return doc.URL
+	}
+	base, _, _ := strings.Cut(doc.URL, "#")
+	return base + "#" + sectionAnchor(heading)
+}
+
+// lastHeading returns the innermost heading of a " > " joined trail.
+func lastHeading(trail string) string {
+	headings := strings.Split(trail, " > ")
+	return headings[len(headings)-1]
+}
+
+// redirectTable holds docs.json redirects by normalized source path.
+// Sources ending in a wildcard segment (/:slug* or /*) match every path
+// below them.
+type redirectTable struct {
+	exact    map[string]string
+	prefixes map[string]string
+}
+
+func newRedirectTable(redirects []Redirect) *redirectTable {
+	table := &redirectTable{exact: make(map[string]string), prefixes: make(map[string]string)}
+	for _, redirect := range redirects {
+		source, sourceWildcard := trimWildcard(redirect.Source)
+		destination, destinationWildcard := trimWildcard(redirect.Destination)
+		if sourceWildcard && destinationWildcard {
+			table.prefixes[normalizePagePath(source)] = destination
+		} else if !sourceWildcard {
+			table.exact[normalizePagePath(source)] = redirect.Destination
+		}
+	}
+	return table
+}
+
+// trimWildcard strips a trailing wildcard segment from a redirect path.
+func trimWildcard(path string) (string, bool) {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		segment := path[i+1:]
+		if segment == "*" || (strings.HasPrefix(segment, ":") && strings.HasSuffix(segment, "*")) {
+			return path[:i], true
+		}
+	}
+	return path, false
+}
+
+// lookup returns where path redirects to, if anywhere.
+func (t *redirectTable) lookup(path string) (string, bool) {
+	path = normalizePagePath(path)
+	if destination, ok := t.exact[path]; ok {
+		return destination, true
+	}
+	// The most specific wildcard wins
+	best, target := "", ""
+	for prefix, destination := range t.prefixes {
+		if rest, ok := strings.CutPrefix(path, prefix+"/"); ok && (target == "" || len(prefix) > len(best)) {
+			best = prefix
+			target = strings.TrimSuffix(destination, "/") + "/" + rest
+		}
+	}
+	return target, target != ""
+}
+
+// follow resolves a public URL through the redirects of its path, returning
+// the final URL and the hops taken.
+func (t *redirectTable) follow(publicURL string) (string, []string, error) {
+	var hops []string
+	seen := map[string]bool{}
+	for {
+		parsed, err := url.Parse(publicURL)
+		if err != nil {
+			return publicURL, hops, err
+		}
+		destination, ok := t.lookup(parsed.Path)
+		if !ok {
+			return publicURL, hops, nil
+		}
+		if seen[parsed.Path] || len(hops) == maxRedirectHops {
+			return publicURL, hops, fmt.Errorf("redirect loop at %s", parsed.Path)
+		}
+		seen[parsed.Path] = true
+
+		target, err := parsed.Parse(destination)
+		if err != nil {
+			return publicURL, hops, fmt.Errorf("invalid redirect destination %q: %w", destination, err)
+		}
+		publicURL = target.String()
+		hops = append(hops, publicURL)
+	}
+}
+
+// checkPublicURLs validates the public URL of every extracted page against
+// docs.json redirects. A URL whose path redirects is replaced by where it
+// ends up. A page that is a redirect destination but whose URL path differs
+// means the template doesn't produce the site's paths.
+func checkPublicURLs(documents []*Document, redirects []Redirect, report *ExtractionReport) {
+	if len(redirects) == 0 {
+		return
+	}
+	table := newRedirectTable(redirects)
+
+	destinations := make(map[string]bool)
+	for _, redirect := range redirects {
+		if destination, wildcard := trimWildcard(redirect.Destination); !wildcard && strings.HasPrefix(destination, "/") {
+			destinations[normalizePagePath(destination)] = true
+		}
+	}
+
+	for _, doc := range documents {
+		if doc.URL == "" {
+			continue
+		}
+		issue := PageIssue{ID: doc.ID, Path: doc.Path, Version: doc.Version, Location: doc.URL}
+
+		final, hops, err := table.follow(doc.URL)
+		switch {
+		case err != nil:
+			issue.Detail = err.Error()
+			report.PublicURLIssues = append(report.PublicURLIssues, issue)
+		case len(hops) > 0:
+			issue.Detail = fmt.Sprintf("redirects to %s, which is used instead", final)
+			report.PublicURLIssues = append(report.PublicURLIssues, issue)
+			doc.URL = final
+		}
+
+		sitePath := publicPagePath(doc.Path)
+		if destinations[sitePath] || destinations[normalizePagePath(doc.Path)] {
+			if parsed, err := url.Parse(doc.URL); err == nil && publicPagePath(parsed.Path) != sitePath {
+				issue.Detail = fmt.Sprintf("docs.json redirects to /%s, but the public URL template gives %s", sitePath, parsed.Path)
+				report.PublicURLIssues = append(report.PublicURLIssues, issue)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandPublicURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		pagePath string
+		version  string
+		want     string
+	}{
+		{name: "page path", template: defaultPublicURL, pagePath: "talos/v1.11/networking/vip", version: "v1.11", want: "https://docs.siderolabs.com/talos/v1.11/networking/vip"},
+		{name: "extension and slashes", template: defaultPublicURL, pagePath: "/talos/v1.11/networking/vip.mdx", version: "v1.11", want: "https://docs.siderolabs.com/talos/v1.11/networking/vip"},
+		{name: "version segment moved", template: "https://www.talos.dev/{version}/{page}/", pagePath: "talos/v1.11/networking/vip", version: "v1.11", want: "https://www.talos.dev/v1.11/talos/networking/vip/"},
+		{name: "version segment only removed whole", template: "https://example.com/{page}", pagePath: "talos/v1.11/v1.11-notes", version: "v1.11", want: "https://example.com/talos/v1.11-notes"},
+		{name: "path without a version segment", template: "https://example.com/{version}/{page}", pagePath: "omni/overview", version: "latest", want: "https://example.com/latest/omni/overview"},
+		{name: "index page", template: defaultPublicURL, pagePath: "talos/v1.11/introduction/index", version: "v1.11", want: "https://docs.siderolabs.com/talos/v1.11/introduction"},
+		{name: "index page without the version", template: "https://example.com/{version}/{page}", pagePath: "talos/v1.11/index.md", version: "v1.11", want: "https://example.com/v1.11/talos"},
+		{name: "root index page", template: defaultPublicURL, pagePath: "index", version: "v1.11", want: "https://docs.siderolabs.com/"},
+		{name: "page named like an index", template: defaultPublicURL, pagePath: "talos/v1.11/reference/indexes", version: "v1.11", want: "https://docs.siderolabs.com/talos/v1.11/reference/indexes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandPublicURL(tt.template, tt.pagePath, tt.version); got != tt.want {
+				t.Errorf("expandPublicURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePublicURL(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  string
+	}{
+		{template: defaultPublicURL},
+		{template: "https://www.talos.dev/{version}/{page}/"},
+		{template: "https://example.com/{slug}", wantErr: "unknown placeholder {slug}"},
+		{template: "/{path}", wantErr: "must expand to an absolute URL"},
+		{template: "{path}", wantErr: "must expand to an absolute URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			err := validatePublicURL(tt.template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validatePublicURL() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validatePublicURL() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSectionURL(t *testing.T) {
+	doc := &Document{URL: "https://docs.siderolabs.com/talos/v1.11/networking/vip"}
+	tests := []struct {
+		name    string
+		doc     *Document
+		heading string
+		want    string
+	}{
+		{name: "page without a heading", doc: doc, want: doc.URL},
+		{name: "heading slug", doc: doc, heading: "Choose your Shared IP", want: doc.URL + "#choose-your-shared-ip"},
+		{name: "punctuation collapses", doc: doc, heading: "Caveats: `etcd` & the VIP (v1.11+)", want: doc.URL + "#caveats-etcd-the-vip-v1-11"},
+		{name: "non-ASCII kept", doc: doc, heading: "Überblick der Netzwerke", want: doc.URL + "#überblick-der-netzwerke"},
+		{name: "explicit anchor", doc: doc, heading: "Machine Config {#machine-config-ref}", want: doc.URL + "#machine-config-ref"},
+		{name: "innermost heading of a trail", doc: doc, heading: lastHeading("VIP > Setup > Layer 2"), want: doc.URL + "#layer-2"},
+		{name: "replaces an existing anchor", doc: &Document{URL: doc.URL + "#old"}, heading: "Setup", want: doc.URL + "#setup"},
+		{name: "document without a URL", doc: &Document{}, heading: "Setup", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sectionURL(tt.doc, tt.heading); got != tt.want {
+				t.Errorf("sectionURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPublicURLs(t *testing.T) {
+	page := func(pagePath string) *Document {
+		return &Document{ID: documentID("v1.11", pagePath), Path: pagePath, Version: "v1.11", URL: expandPublicURL(defaultPublicURL, pagePath, "v1.11")}
+	}
+	moved := page("talos/v1.11/install")
+	intro := page("talos/v1.11/introduction/index")
+	wrong := page("talos/v1.11/networking/vip")
+	wrong.URL = "https://docs.siderolabs.com/v1.11/networking/vip"
+
+	redirects := []Redirect{
+		{Source: "/talos/v1.11/install", Destination: "/talos/v1.11/getting-started/install"},
+		{Source: "/talos/v1.11/intro", Destination: "/talos/v1.11/introduction"},
+		{Source: "/talos/v1.11/vip", Destination: "/talos/v1.11/networking/vip"},
+	}
+	report := newExtractionReport()
+	checkPublicURLs([]*Document{moved, intro, wrong}, redirects, report)
+
+	if moved.URL != "https://docs.siderolabs.com/talos/v1.11/getting-started/install" {
+		t.Errorf("redirected page URL = %q, want the redirect destination", moved.URL)
+	}
+	if intro.URL != "https://docs.siderolabs.com/talos/v1.11/introduction" {
+		t.Errorf("index page URL = %q", intro.URL)
+	}
+	if len(report.PublicURLIssues) != 2 || report.PublicURLIssues[0].ID != moved.ID || report.PublicURLIssues[1].ID != wrong.ID {
+		t.Errorf("public URL issues %+v, want one for the redirected page and one for the mismatched template", report.PublicURLIssues)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// bucketIdleTimeout is how long an untouched, refilled bucket is kept before
+// it's pruned, so sessions that come and go don't grow the limiter forever.
+const bucketIdleTimeout = 10 * time.Minute
+
+// tokenBucket refills continuously at rate tokens per second up to burst.
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter keeps one token bucket per key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    float64(limit.RequestsPerMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token for key. When none is left it returns how long until
+// one is.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*l.rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait.Round(time.Second)
+}
+
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastSeen) > bucketIdleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// toolLimits applies the per-client limit across all tools and the per-tool
+// limits, which also count per client so one busy client can't use up a
+// tool for everyone else.
+type toolLimits struct {
+	perClient *rateLimiter
+	perTool   map[string]*rateLimiter
+}
+
+func newToolLimits(config LimitsConfig) *toolLimits {
+	limits := &toolLimits{perTool: make(map[string]*rateLimiter)}
+	if config.PerClient.RequestsPerMinute > 0 {
+		limits.perClient = newRateLimiter(config.PerClient)
+	}
+	for tool, limit := range config.Tools {
+		if limit.RequestsPerMinute > 0 {
+			limits.perTool[tool] = newRateLimiter(limit)
+		}
+	}
+	return limits
+}
+
+func (t *toolLimits) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client := clientID(ctx)
+		tool := request.Params.Name
+
+		if t.perClient != nil {
+			if ok, wait := t.perClient.allow(client); !ok {
+				log.Printf("Rate limited %s calling %s", client, tool)
+				return mcp.NewToolResultError(fmt.Sprintf("rate limit exceeded for this client, retry in %s", wait)), nil
+			}
+		}
+		if limiter, exists := t.perTool[tool]; exists {
+			if ok, wait := limiter.allow(client); !ok {
+				log.Printf("Rate limited %s calling %s", client, tool)
+				return mcp.NewToolResultError(fmt.Sprintf("rate limit exceeded for %s, retry in %s", tool, wait)), nil
+			}
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// clientID identifies the caller for rate limiting: by API key when the
+// transport is authenticated, otherwise by MCP session.
+func clientID(ctx context.Context) string {
+	if key := apiKeyFromContext(ctx); key != nil {
+		return "key:" + key.Name
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return "session:" + session.SessionID()
+	}
+	return "anonymous"
+}
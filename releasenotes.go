@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	changeFeature     = "feature"
+	changeDeprecation = "deprecation"
+	changeBreaking    = "breaking"
+	changeFix         = "fix"
+)
+
+// changeHeadingKeywords mark headings that group changes by type rather
+// than naming a component, e.g. "Breaking Changes" or "Bug Fixes".
+var changeHeadingKeywords = []string{
+	"breaking", "deprecat", "removed", "removal", "fix", "feature",
+	"change", "highlight", "improvement", "notable", "other",
+}
+
+// ReleaseNote is one change listed on a what's-new or release notes page.
+type ReleaseNote struct {
+	Version    string `json:"version"`
+	Component  string `json:"component"`
+	ChangeType string `json:"change_type"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	Section    string `json:"section"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+}
+
+// isReleaseNotesPage recognises what's-new and release notes pages by path
+// or title.
+func isReleaseNotesPage(doc *Document) bool {
+	return matchesAny(doc.Path+" "+doc.Title, whatsNewKeywords)
+}
+
+// parseReleaseNotes splits a release notes page into one entry per section,
+// or per bullet when a section is a plain list. The change type comes from
+// the headings above the entry and the component from the first heading
+// that isn't a change type.
+func parseReleaseNotes(doc *Document) []ReleaseNote {
+	var notes []ReleaseNote
+	pageTitle := ""
+	for _, section := range splitSections(doc.Content) {
+		if section.Level == 1 {
+			pageTitle = section.Heading
+		}
+		if section.Level <= 1 {
+			continue
+		}
+
+		trail := strings.Split(strings.TrimPrefix(section.Trail, pageTitle+" > "), " > ")
+		changeType := classifyChange(strings.Join(trail, " "))
+		component := "general"
+		for _, heading := range trail {
+			if !matchesAny(heading, changeHeadingKeywords) {
+				component = heading
+				break
+			}
+		}
+
+		for _, item := range releaseNoteItems(section) {
+			notes = append(notes, ReleaseNote{
+				Version:    doc.Version,
+				Component:  component,
+				ChangeType: changeType,
+				Title:      section.Heading,
+				Text:       item.text,
+				Section:    section.Trail,
+				StartLine:  item.startLine,
+				EndLine:    item.endLine,
+			})
+		}
+	}
+	return notes
+}
+
+// classifyChange picks the change type named by a heading trail.
+func classifyChange(headings string) string {
+	switch {
+	case matchesAny(headings, []string{"breaking", "incompatib"}):
+		return changeBreaking
+	case matchesAny(headings, []string{"deprecat"}):
+		return changeDeprecation
+	case matchesAny(headings, []string{"removed", "removal", "no longer"}):
+		return changeBreaking
+	case matchesAny(headings, []string{"fix", "bug", "regression"}):
+		return changeFix
+	default:
+		return changeFeature
+	}
+}
+
+type releaseNoteItem struct {
+	text               string
+	startLine, endLine int
+}
+
+// releaseNoteItems returns the section's text as one item, or one item per
+// top-level bullet if every unindented line is a bullet.
+func releaseNoteItems(section markdownSection) []releaseNoteItem {
+	lines := strings.Split(strings.TrimSuffix(section.Body, "\n"), "\n")[1:]
+	firstLine := section.StartLine + 1
+
+	var items []releaseNoteItem
+	list := true
+	for i, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+		case isBullet(line):
+			items = append(items, releaseNoteItem{startLine: firstLine + i})
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			if len(items) == 0 {
+				list = false
+			}
+		default:
+			list = false
+		}
+	}
+
+	if !list || len(items) == 0 {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text == "" {
+			return nil
+		}
+		// Cite the section up to its last line of text, like list items
+		end := section.EndLine
+		for end > section.StartLine && strings.TrimSpace(lines[end-firstLine]) == "" {
+			end--
+		}
+		return []releaseNoteItem{{text: text, startLine: section.StartLine, endLine: end}}
+	}
+
+	for i := range items {
+		end := section.EndLine
+		if i+1 < len(items) {
+			end = items[i+1].startLine - 1
+		}
+		for end > items[i].startLine && strings.TrimSpace(lines[end-firstLine]) == "" {
+			end--
+		}
+		items[i].endLine = end
+
+		text := strings.Join(lines[items[i].startLine-firstLine:end-firstLine+1], "\n")
+		text = strings.TrimSpace(text)
+		items[i].text = strings.TrimSpace(text[2:])
+	}
+	return items
+}
+
+func isBullet(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ")
+}
+
+// releaseNoteCitation cites the lines a release note was taken from.
+func releaseNoteCitation(doc *Document, note ReleaseNote) Citation {
+	return Citation{
+		DocumentID: doc.ID,
+		Title:      doc.Title,
+		URI:        documentResourceURI(doc),
+		URL:        sectionURL(doc, note.Title),
+		Section:    note.Section,
+		Lines:      fmt.Sprintf("%d-%d", note.StartLine, note.EndLine),
+	}
+}
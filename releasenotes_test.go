@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// lineOf returns the 1-based line of the first occurrence of text.
+func lineOf(t *testing.T, content, text string) int {
+	t.Helper()
+	i := strings.Index(content, text)
+	if i < 0 {
+		t.Fatalf("%q not found in fixture", text)
+	}
+	return strings.Count(content[:i], "\n") + 1
+}
+
+func TestParseReleaseNotes(t *testing.T) {
+	type note struct {
+		component, changeType, title, text string
+		// lines is the text of the first and last line the note spans
+		first, last string
+	}
+	tests := []struct {
+		file, path, title string
+		want              []note
+	}{
+		{
+			file:  "testdata/whats-new.mdx",
+			path:  "talos/v1.11/getting-started/whats-new-in-talos",
+			title: "What's New in Talos 1.11.0",
+			want: []note{
+				{"Component Updates", changeFeature, "Component Updates", "Linux: 6.12.40", "- Linux", "- Linux"},
+				{"Component Updates", changeFeature, "Component Updates", "Kubernetes: 1.34.0", "- Kubernetes", "- Kubernetes"},
+				{"Component Updates", changeFeature, "Component Updates", "etcd: 3.6.4", "- etcd", "- etcd"},
+				{"KubeSpan", changeFeature, "KubeSpan", "KubeSpan now supports filtering advertised endpoints.\nThe new `advertisedNetworks` field controls it.", "## KubeSpan", "The new"},
+				{"Network", changeBreaking, "Network", "The `.machine.network.interfaces[].vlans` field is removed in favour of `VLANConfig` documents.", "### Network", "The `.machine.network"},
+				{"general", changeDeprecation, "Deprecations", "`talosctl cluster create` with `--with-uefi=false` is deprecated.\n  It will be removed in Talos 1.12.", "- `talosctl", "  It will be removed"},
+				{"general", changeDeprecation, "Deprecations", "The `.machine.install.bootloader` field is deprecated.", "- The `.machine.install", "- The `.machine.install"},
+			},
+		},
+		{
+			file:  "testdata/release-notes.md",
+			path:  "talos/v1.11/reference/release-notes",
+			title: "Release Notes",
+			want: []note{
+				{"general", changeFix, "Bug Fixes", "Fix a race in the apid proxy. ([#11234](https://github.com/siderolabs/talos/pull/11234))", "- Fix a race", "- Fix a race"},
+				{"general", changeFix, "Bug Fixes", "Correct the MTU on bonded interfaces.", "- Correct", "- Correct"},
+				{"Image Factory", changeFeature, "Image Factory", "Image Factory schematics can now include overlays.", "### Image Factory", "Image Factory schematics"},
+				{"general", changeFeature, "Other", "Talos now ships with containerd 2.1.", "## Other", "Talos now ships"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			doc := loadTestDocument(t, tt.file, tt.path, tt.title)
+			if !isReleaseNotesPage(doc) {
+				t.Fatalf("%s is not recognised as a release notes page", tt.path)
+			}
+
+			notes := parseReleaseNotes(doc)
+			if len(notes) != len(tt.want) {
+				for _, n := range notes {
+					t.Logf("%+v", n)
+				}
+				t.Fatalf("parsed %d notes, want %d", len(notes), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := notes[i]
+				if got.Component != want.component || got.ChangeType != want.changeType || got.Title != want.title || got.Text != want.text {
+					t.Errorf("note %d = %s/%s %q: %q, want %s/%s %q: %q",
+						i, got.Component, got.ChangeType, got.Title, got.Text, want.component, want.changeType, want.title, want.text)
+				}
+				if got.Version != "v1.11" {
+					t.Errorf("note %d has version %q", i, got.Version)
+				}
+				first, last := lineOf(t, doc.Content, want.first), lineOf(t, doc.Content, want.last)
+				if got.StartLine != first || got.EndLine != last {
+					t.Errorf("note %d spans lines %d-%d, want %d-%d", i, got.StartLine, got.EndLine, first, last)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyChange(t *testing.T) {
+	tests := map[string]string{
+		"Breaking Changes Network":     changeBreaking,
+		"Incompatible API changes":     changeBreaking,
+		"Deprecations":                 changeDeprecation,
+		"Removed features":             changeBreaking,
+		"Bug Fixes":                    changeFix,
+		"Regressions":                  changeFix,
+		"KubeSpan":                     changeFeature,
+		"Deprecated and removed flags": changeDeprecation,
+	}
+	for headings, want := range tests {
+		if got := classifyChange(headings); got != want {
+			t.Errorf("classifyChange(%q) = %s, want %s", headings, got, want)
+		}
+	}
+}
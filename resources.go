@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// resourceScheme prefixes document resource URIs; the rest of the URI is
+	// the document ID, i.e. <version>/<page path>
+	resourceScheme = "talos-docs://"
+
+	// resourcePageSize caps how many entries a single list request returns
+	resourcePageSize = 100
+
+	markdownMIMEType = "text/markdown"
+)
+
+// registeredResource is what refreshResources last registered for a URI.
+type registeredResource struct {
+	name string
+	url  string
+	hash string
+}
+
+func documentResourceURI(doc *Document) string {
+	return resourceScheme + doc.ID
+}
+
+// registerResources adds the document URI template. Concrete resources are
+// registered per document by refreshResources once the index is loaded.
+func (s *TalosDocMCPServer) registerResources() {
+	template := mcp.NewResourceTemplate(
+		resourceScheme+"{version}/{+path}",
+		"Talos documentation page",
+		mcp.WithTemplateDescription("A Talos documentation page by version and page path, e.g. talos-docs://v1.11/talos/v1.11/networking/vip"),
+		mcp.WithTemplateMIMEType(markdownMIMEType),
+	)
+
+	s.mcpServer.AddResourceTemplate(template, s.handleReadDocumentTemplate)
+}
+
+// refreshResources brings the registered resources in line with the index
+// after a (re)load. Pages are diffed by content hash against the previous
+// refresh: added and removed pages change the registration, which notifies
+// clients with list_changed, and pages whose content changed get a
+// resources/updated notification.
+//
+// mcp-go doesn't route resources/subscribe requests, so updates go to every
+// connected client rather than just subscribers.
+func (s *TalosDocMCPServer) refreshResources() {
+	documents := s.searchEngine.ListDocuments()
+
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+
+	registered := make(map[string]registeredResource, len(documents))
+	var added []server.ServerResource
+	var updated []string
+	for _, doc := range documents {
+		resource := documentResource(doc)
+		current := registeredResource{name: resource.Name, url: doc.URL, hash: doc.ContentHash}
+		if current.hash == "" {
+			current.hash = contentHash(doc.Content)
+		}
+		registered[resource.URI] = current
+
+		previous, exists := s.resources[resource.URI]
+		if exists && previous.hash != current.hash {
+			updated = append(updated, resource.URI)
+		}
+		// A retitled or moved page needs its listing entry replaced too
+		if !exists || previous.name != current.name || previous.url != current.url {
+			added = append(added, server.ServerResource{
+				Resource: resource,
+				Handler:  s.handleReadDocument,
+			})
+		}
+	}
+
+	var removed []string
+	for uri := range s.resources {
+		if _, exists := registered[uri]; !exists {
+			removed = append(removed, uri)
+		}
+	}
+
+	if len(removed) > 0 {
+		s.mcpServer.DeleteResources(removed...)
+	}
+	if len(added) > 0 {
+		s.mcpServer.AddResources(added...)
+	}
+	for _, uri := range updated {
+		s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+			"uri": uri,
+		})
+	}
+
+	s.resources = registered
+	log.Printf("Resources refreshed: %d added, %d updated, %d removed", len(added), len(updated), len(removed))
+}
+
+func documentResource(doc *Document) mcp.Resource {
+	// Kept free of anything that changes with content (such as the update
+	// date) so edited pages don't need re-registering
+	description := fmt.Sprintf("%s documentation, %s section", doc.Version, doc.Section)
+
+	// Names sort the paginated listing and double as cursors, so they must
+	// be unique; titles alone repeat across versions
+	resource := mcp.NewResource(
+		documentResourceURI(doc),
+		fmt.Sprintf("%s [%s]", doc.Title, doc.ID),
+		mcp.WithResourceDescription(description),
+		mcp.WithMIMEType(markdownMIMEType),
+	)
+	resource.Meta = documentMeta(doc)
+	return resource
+}
+
+// documentMeta carries the page's public URL in a resource's _meta.
+func documentMeta(doc *Document) *mcp.Meta {
+	if doc.URL == "" {
+		return nil
+	}
+	return mcp.NewMetaFromMap(map[string]any{"url": doc.URL})
+}
+
+func (s *TalosDocMCPServer) handleReadDocument(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, resourceScheme)
+	doc, exists := s.searchEngine.GetDocument(id)
+	if !exists {
+		return nil, fmt.Errorf("document %s is no longer indexed", id)
+	}
+
+	return documentContents(doc), nil
+}
+
+func (s *TalosDocMCPServer) handleReadDocumentTemplate(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	version := templateArgument(request.Params.Arguments["version"])
+	pagePath := templateArgument(request.Params.Arguments["path"])
+
+	doc, exists := s.searchEngine.GetDocumentByPath(pagePath, version)
+	if !exists {
+		return nil, fmt.Errorf("no %s document at path %s", version, pagePath)
+	}
+
+	return documentContents(doc), nil
+}
+
+func documentContents(doc *Document) []mcp.ResourceContents {
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			Meta:     documentMeta(doc),
+			URI:      documentResourceURI(doc),
+			MIMEType: markdownMIMEType,
+			Text:     doc.Content,
+		},
+	}
+}
+
+// templateArgument unwraps a matched URI template variable, which mcp-go
+// passes as a string or, for list values, a []string.
+func templateArgument(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "/")
+	default:
+		return ""
+	}
+}
@@ -2,27 +2,44 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
 	index "github.com/blevesearch/bleve_index_api"
 )
 
 type SearchEngine struct {
-	activeIndex   bleve.Index
-	stagingIndex  bleve.Index
 	indexPath     string
-	mu            sync.RWMutex
-	documents     map[string]*Document
-	taxonomy      *ContentTaxonomy
+	current       atomic.Pointer[indexGeneration]
 	maxResults    int
 	snippetLength int
+
+	// retainedGenerations is how many generations, the current one
+	// included, are kept on disk for rollback
+	retainedGenerations int
+	// maxDocumentDrop is the largest drop in document count, in percent, a
+	// rebuilt generation may have before it is rejected; 0 disables the check
+	maxDocumentDrop float64
+
+	// writeMu serializes rebuilds and imports; searches never take it
+	writeMu sync.Mutex
+	// lock is the cross-process writer lock on indexPath, nil when the
+	// index is open read-only
+	lock     *os.File
+	readOnly bool
+	// open holds the generations this process opened that may still be
+	// draining, guarded by writeMu
+	open map[string]*indexGeneration
 }
 
 type ContentTaxonomy struct {
@@ -48,18 +65,39 @@ type SearchResponse struct {
 	Duration  time.Duration   `json:"duration"`
 }
 
-func NewSearchEngine(indexPath string, maxResults, snippetLength int) (*SearchEngine, error) {
+// errReadOnlyIndex is returned by writes to an index opened read-only.
+var errReadOnlyIndex = fmt.Errorf("index is open read-only")
+
+// documentStoreFile holds the serialized documents next to the bleve files so
+// the in-memory store survives restarts and travels with the index on swaps.
+const documentStoreFile = "documents.json"
+
+// maxSearchResults caps the results of one search to keep JSON-RPC response
+// size manageable.
+const maxSearchResults = 5
+
+func newContentTaxonomy() *ContentTaxonomy {
+	return &ContentTaxonomy{
+		Versions:  make(map[string]bool),
+		Sections:  make(map[string]bool),
+		Platforms: make(map[string]bool),
+		Tags:      make(map[string]bool),
+	}
+}
+
+func NewSearchEngine(indexPath string, maxResults, snippetLength, retainedGenerations int, maxDocumentDrop float64) (*SearchEngine, error) {
+	// The current generation plus at least one to roll back to
+	if retainedGenerations < 2 {
+		retainedGenerations = 2
+	}
+
 	se := &SearchEngine{
-		indexPath:     indexPath,
-		documents:     make(map[string]*Document),
-		taxonomy:      &ContentTaxonomy{
-			Versions:  make(map[string]bool),
-			Sections:  make(map[string]bool),
-			Platforms: make(map[string]bool),
-			Tags:      make(map[string]bool),
-		},
-		maxResults:    maxResults,
-		snippetLength: snippetLength,
+		indexPath:           indexPath,
+		maxResults:          maxResults,
+		snippetLength:       snippetLength,
+		retainedGenerations: retainedGenerations,
+		maxDocumentDrop:     maxDocumentDrop,
+		open:                make(map[string]*indexGeneration),
 	}
 
 	// Create parent directory if it doesn't exist
@@ -67,173 +105,143 @@ func NewSearchEngine(indexPath string, maxResults, snippetLength int) (*SearchEn
 		return nil, fmt.Errorf("failed to create index directory: %w", err)
 	}
 
-	// Open indices immediately - MUST happen before stdio transport starts
-	log.Printf("Opening bleve indices...")
-	activeIndexPath := filepath.Join(indexPath, "active")
-
-	// Try to open with read-only config to avoid file locking issues
-	config := map[string]interface{}{
-		"read_only": true,
-	}
-	activeIndex, err := bleve.OpenUsing(activeIndexPath, config)
-	if err == bleve.ErrorIndexPathDoesNotExist {
-		log.Printf("No existing index found, creating empty index")
-		activeIndex, err = bleve.New(activeIndexPath, bleve.NewIndexMapping())
-		if err != nil {
-			return nil, fmt.Errorf("failed to create search index: %w", err)
-		}
-	} else if err != nil {
-		log.Printf("Failed to open in read-only mode, trying regular open: %v", err)
-		// Fallback to regular open
-		activeIndex, err = bleve.Open(activeIndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open existing index: %w", err)
-		}
+	// Only one process may build, import, roll back or recover an index
+	// directory at a time; the lock is held until Close or exit
+	lock, err := lockIndex(indexPath)
+	if err != nil {
+		return nil, err
 	}
+	se.lock = lock
 
-	docCount, _ := activeIndex.DocCount()
-	log.Printf("Opened existing index with %d documents", docCount)
-	se.activeIndex = activeIndex
-
-	// Create staging index
-	stagingPath := filepath.Join(indexPath, "staging")
-	os.RemoveAll(stagingPath) // Clean up any old staging
-	stagingIndex, err := bleve.New(stagingPath, bleve.NewIndexMapping())
+	// Open indices immediately - MUST happen before stdio transport starts
+	log.Printf("Opening bleve indices...")
+	generation, err := se.openGenerations()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create staging index: %w", err)
+		lock.Close()
+		return nil, err
 	}
-	se.stagingIndex = stagingIndex
+	se.current.Store(generation)
+	se.open[generation.id] = generation
 
 	log.Printf("SearchEngine ready")
 	return se, nil
 }
 
-func (se *SearchEngine) EnsureIndicesOpen() error {
-	// Quick check with read lock first (avoid contention)
-	se.mu.RLock()
-	if se.activeIndex != nil && se.stagingIndex != nil {
-		se.mu.RUnlock()
-		return nil
+// OpenSearchEngineReadOnly opens the current generation of an index for
+// reading without taking the writer lock, so it can run next to a live
+// server. Nothing on disk is changed: no migration, no recovery rewrite of
+// the manifest and no empty index when there is none.
+func OpenSearchEngineReadOnly(indexPath string, maxResults, snippetLength int) (*SearchEngine, error) {
+	if _, err := os.Stat(filepath.Join(indexPath, generationsDir)); err != nil {
+		return nil, fmt.Errorf("no index generations at %s: %w", indexPath, err)
 	}
-	se.mu.RUnlock()
-
-	// Need to open indices - acquire write lock
-	se.mu.Lock()
-	defer se.mu.Unlock()
 
-	// Double-check after acquiring write lock (another goroutine may have opened them)
-	if se.activeIndex != nil && se.stagingIndex != nil {
-		return nil
+	se := &SearchEngine{
+		indexPath:     indexPath,
+		maxResults:    maxResults,
+		snippetLength: snippetLength,
+		readOnly:      true,
+		open:          make(map[string]*indexGeneration),
 	}
 
-	log.Printf("Opening bleve indices...")
-
-	// Open or create the active index
-	activeIndexPath := filepath.Join(se.indexPath, "active")
-	if se.activeIndex == nil {
-		activeIndex, err := bleve.Open(activeIndexPath)
-		if err == bleve.ErrorIndexPathDoesNotExist {
-			log.Printf("No existing index found, will create on first indexing")
-			activeIndex, err = bleve.New(activeIndexPath, bleve.NewIndexMapping())
-			if err != nil {
-				return fmt.Errorf("failed to create search index: %w", err)
-			}
-		} else if err != nil {
-			// Index might be corrupted, try to recover
-			log.Printf("Warning: failed to open existing index: %v", err)
-			log.Printf("Removing corrupted index and creating new one...")
-			if err := os.RemoveAll(activeIndexPath); err != nil {
-				return fmt.Errorf("failed to remove corrupted index: %w", err)
-			}
-			activeIndex, err = bleve.New(activeIndexPath, bleve.NewIndexMapping())
-			if err != nil {
-				return fmt.Errorf("failed to create search index: %w", err)
-			}
-		} else {
-			log.Printf("Opened existing search index at %s", activeIndexPath)
-			// Get document count from the index
-			docCount, err := activeIndex.DocCount()
-			if err == nil && docCount > 0 {
-				log.Printf("Found %d documents in existing index", docCount)
-			}
-		}
-		se.activeIndex = activeIndex
+	generation, err := se.openGenerations()
+	if err != nil {
+		return nil, err
 	}
+	se.current.Store(generation)
+	se.open[generation.id] = generation
+	return se, nil
+}
 
-	// Create staging index (don't fail if it exists)
-	if se.stagingIndex == nil {
-		stagingPath := filepath.Join(se.indexPath, "staging")
-		if err := os.RemoveAll(stagingPath); err != nil {
-			log.Printf("Warning: failed to remove staging index: %v", err)
-		}
+// Close closes the open generations and releases the writer lock.
+func (se *SearchEngine) Close() error {
+	se.writeMu.Lock()
+	defer se.writeMu.Unlock()
 
-		stagingIndex, err := bleve.New(stagingPath, bleve.NewIndexMapping())
-		if err != nil {
-			return fmt.Errorf("failed to create staging index: %w", err)
-		}
-		se.stagingIndex = stagingIndex
+	for id, generation := range se.open {
+		generation.retire(false)
+		delete(se.open, id)
+	}
+	if se.lock != nil {
+		err := se.lock.Close()
+		se.lock = nil
+		return err
 	}
-
-	log.Printf("Bleve indices opened successfully")
 	return nil
 }
 
-func (se *SearchEngine) IndexDocuments(documents []*Document) error {
-	se.mu.Lock()
-	defer se.mu.Unlock()
+// IndexDocuments builds a new index generation from documents at commitSHA
+// and publishes it if it passes the health check. Searches keep using the
+// current generation until the swap.
+func (se *SearchEngine) IndexDocuments(documents []*Document, commitSHA string, progress ProgressFunc) error {
+	if se.readOnly {
+		return errReadOnlyIndex
+	}
+	se.writeMu.Lock()
+	defer se.writeMu.Unlock()
 
 	start := time.Now()
 	log.Printf("Starting indexing of %d documents...", len(documents))
 
-	// Clear staging index by creating a new one
+	// Build in staging so an interrupted build never looks like a generation
 	stagingPath := filepath.Join(se.indexPath, "staging")
 	if err := os.RemoveAll(stagingPath); err != nil {
 		log.Printf("Warning: failed to clear staging index: %v", err)
 	}
 
-	// Close existing staging index if it exists
-	if se.stagingIndex != nil {
-		se.stagingIndex.Close()
-	}
-
-	// Create staging directory structure
-	if err := os.MkdirAll(stagingPath, 0755); err != nil {
-		return fmt.Errorf("failed to create staging directory: %w", err)
-	}
-
-	newStagingIndex, err := bleve.New(stagingPath, bleve.NewIndexMapping())
+	stagingIndex, err := bleve.New(stagingPath, bleve.NewIndexMapping())
 	if err != nil {
 		return fmt.Errorf("failed to create new staging index: %w", err)
 	}
-	se.stagingIndex = newStagingIndex
 
-	// Index documents in staging
+	// Index documents in staging, building a fresh store so pages removed
+	// upstream don't linger after the swap
+	newDocuments := make(map[string]*Document, len(documents))
 	indexed := 0
 	for i, doc := range documents {
-		if err := se.indexDocument(newStagingIndex, doc); err != nil {
-			log.Printf("Error indexing document %s: %v", doc.ID, err)
+		// Indexing the same ID twice would silently replace the first page
+		if existing, exists := newDocuments[doc.ID]; exists {
+			log.Printf("Warning: duplicate document ID %s (%s and %s), keeping the first", doc.ID, existing.Path, doc.Path)
 			continue
 		}
 
-		// Update taxonomy
-		se.updateTaxonomy(doc)
+		if err := se.indexDocument(stagingIndex, doc); err != nil {
+			log.Printf("Error indexing document %s: %v", doc.ID, err)
+			continue
+		}
 
 		// Store in memory map
-		se.documents[doc.ID] = doc
+		newDocuments[doc.ID] = doc
 		indexed++
 
 		// Progress update every 100 documents
 		if (i+1)%100 == 0 {
 			log.Printf("  Indexed %d/%d documents...", i+1, len(documents))
+			progress.report("indexing %d/%d", i+1, len(documents))
 		}
 	}
 
 	log.Printf("Indexing complete: %d documents indexed", indexed)
+
+	if err := stagingIndex.Close(); err != nil {
+		return fmt.Errorf("failed to close staging index: %w", err)
+	}
+	if err := saveDocumentStore(stagingPath, newDocuments); err != nil {
+		return fmt.Errorf("failed to save document store: %w", err)
+	}
+
 	log.Printf("Performing atomic index swap...")
+	progress.report("swapping index")
 
-	// Atomic swap
-	if err := se.atomicSwap(); err != nil {
-		return fmt.Errorf("failed to atomic swap indices: %w", err)
+	info := GenerationInfo{
+		CommitSHA:      commitSHA,
+		DocumentCount:  len(newDocuments),
+		BuiltAt:        time.Now().UTC(),
+		Source:         generationSourceSync,
+		DocumentSchema: documentSchemaVersion,
+	}
+	if _, err := se.promoteStaging(stagingPath, info, true); err != nil {
+		return err
 	}
 
 	log.Printf("Index ready! Total time: %v", time.Since(start))
@@ -255,101 +263,104 @@ func (se *SearchEngine) indexDocument(index bleve.Index, doc *Document) error {
 	return index.Index(doc.ID, indexDoc)
 }
 
-func (se *SearchEngine) updateTaxonomy(doc *Document) {
+func updateTaxonomy(taxonomy *ContentTaxonomy, doc *Document) {
 	if doc.Version != "" {
-		se.taxonomy.Versions[doc.Version] = true
+		taxonomy.Versions[doc.Version] = true
 	}
 	if doc.Section != "" {
-		se.taxonomy.Sections[doc.Section] = true
+		taxonomy.Sections[doc.Section] = true
 	}
 	if doc.Platform != "" {
-		se.taxonomy.Platforms[doc.Platform] = true
+		taxonomy.Platforms[doc.Platform] = true
 	}
 	for _, tag := range doc.Tags {
-		se.taxonomy.Tags[tag] = true
+		taxonomy.Tags[tag] = true
 	}
 }
 
-func (se *SearchEngine) atomicSwap() error {
-	log.Printf("  Closing indices...")
-	// Close BOTH indices before filesystem operations
-	if se.activeIndex != nil {
-		se.activeIndex.Close()
+// saveDocumentStore writes the documents, sorted by ID, into an index directory.
+func saveDocumentStore(dir string, documents map[string]*Document) error {
+	ids := make([]string, 0, len(documents))
+	for id := range documents {
+		ids = append(ids, id)
 	}
-	if se.stagingIndex != nil {
-		se.stagingIndex.Close()
-	}
-
-	// Define paths
-	activeIndexPath := filepath.Join(se.indexPath, "active")
-	stagingPath := filepath.Join(se.indexPath, "staging")
-	backupPath := filepath.Join(se.indexPath, "backup")
+	sort.Strings(ids)
 
-	log.Printf("  Backing up old index...")
-	// Remove old backup if it exists
-	if err := os.RemoveAll(backupPath); err != nil {
-		log.Printf("Warning: failed to remove old backup index: %v", err)
+	ordered := make([]*Document, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, documents[id])
 	}
 
-	// Backup current active index
-	if err := os.Rename(activeIndexPath, backupPath); err != nil {
-		log.Printf("Warning: failed to backup active index: %v", err)
-	}
-
-	log.Printf("  Swapping staging to active...")
-	// Move staging to active
-	if err := os.Rename(stagingPath, activeIndexPath); err != nil {
-		return fmt.Errorf("failed to move staging index to active: %w", err)
+	data, err := json.Marshal(ordered)
+	if err != nil {
+		return fmt.Errorf("failed to marshal documents: %w", err)
 	}
+	return os.WriteFile(filepath.Join(dir, documentStoreFile), data, 0644)
+}
 
-	log.Printf("  Reopening active index...")
-	// Reopen active index
-	activeIndex, err := bleve.Open(activeIndexPath)
+// readDocumentStore reads the documents saved by saveDocumentStore.
+func readDocumentStore(dir string) ([]*Document, error) {
+	data, err := os.ReadFile(filepath.Join(dir, documentStoreFile))
 	if err != nil {
-		return fmt.Errorf("failed to reopen active index: %w", err)
+		return nil, err
 	}
-	se.activeIndex = activeIndex
 
-	log.Printf("  Creating new staging index...")
-	// Create new staging index
-	newStagingIndex, err := bleve.New(stagingPath, bleve.NewIndexMapping())
-	if err != nil {
-		return fmt.Errorf("failed to create new staging index: %w", err)
+	var documents []*Document
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", documentStoreFile, err)
 	}
-	se.stagingIndex = newStagingIndex
+	return documents, nil
+}
 
-	log.Printf("  Swap complete!")
-	return nil
+func documentsByID(documents []*Document) map[string]*Document {
+	byID := make(map[string]*Document, len(documents))
+	for _, doc := range documents {
+		byID[doc.ID] = doc
+	}
+	return byID
 }
 
 func (se *SearchEngine) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
 	log.Printf("DEBUG: Search called for query: %s", req.Query)
-	se.mu.RLock()
-	log.Printf("DEBUG: Acquired read lock")
-	defer func() {
-		se.mu.RUnlock()
-		log.Printf("DEBUG: Released read lock")
-	}()
+	generation := se.acquireCurrent()
+	defer generation.release()
 
 	start := time.Now()
 
 	// Build simple query
-	query := bleve.NewMatchQuery(req.Query)
+	var searchQuery query.Query = bleve.NewMatchQuery(req.Query)
+	if req.Version != "" {
+		// Restrict to the version in the index so hits from other versions
+		// don't use up the candidates before the filters below
+		versionQuery := bleve.NewMatchQuery(req.Version)
+		versionQuery.SetField("version")
+		versionQuery.SetOperator(query.MatchQueryOperatorAnd)
+		searchQuery = bleve.NewConjunctionQuery(searchQuery, versionQuery)
+	}
+	if req.Platform != "" {
+		// Likewise for the platform, so pages of other platforms that match
+		// the query better don't crowd the requested one out
+		platformQuery := bleve.NewMatchQuery(req.Platform)
+		platformQuery.SetField("platform")
+		platformQuery.SetOperator(query.MatchQueryOperatorAnd)
+		searchQuery = bleve.NewConjunctionQuery(searchQuery, platformQuery)
+	}
 	log.Printf("DEBUG: Built match query")
 	
 	// Create search request with strict limits to avoid buffer overflow
-	// Limit to max 5 results to keep JSON-RPC response size manageable
 	limit := req.Limit
-	if limit == 0 || limit > 5 {
-		limit = 5
+	if limit == 0 || limit > maxSearchResults {
+		limit = maxSearchResults
 	}
-	searchReq := bleve.NewSearchRequest(query)
-	searchReq.Size = limit
+	// Fetch a few times more hits than needed so backlinks can lift a hub
+	// page above a stub that matches slightly better
+	searchReq := bleve.NewSearchRequest(searchQuery)
+	searchReq.Size = limit * linkRankCandidates
 	searchReq.From = 0
 
 	// Execute search
 	log.Printf("DEBUG: About to execute search in context")
-	searchResult, err := se.activeIndex.SearchInContext(ctx, searchReq)
+	searchResult, err := generation.index.SearchInContext(ctx, searchReq)
 	if err != nil {
 		log.Printf("DEBUG: Search failed: %v", err)
 		return nil, fmt.Errorf("search failed: %w", err)
@@ -360,29 +371,11 @@ func (se *SearchEngine) Search(ctx context.Context, req *SearchRequest) (*Search
 	results := make([]*SearchResult, 0, len(searchResult.Hits))
 	for _, hit := range searchResult.Hits {
 		// Try to get from memory first (faster)
-		doc, exists := se.documents[hit.ID]
-		if exists {
-			// Limit content size for in-memory documents too (10KB per doc)
-			if len(doc.Content) > 10000 {
-				// Create a copy with truncated content
-				doc = &Document{
-					ID:          doc.ID,
-					Title:       doc.Title,
-					Content:     doc.Content[:10000] + "\n\n[Content truncated]",
-					Path:        doc.Path,
-					Version:     doc.Version,
-					Section:     doc.Section,
-					Platform:    doc.Platform,
-					Tags:        doc.Tags,
-					LastUpdated: doc.LastUpdated,
-					Metadata:    doc.Metadata,
-				}
-			}
-		}
+		doc, exists := generation.documents[hit.ID]
 		if !exists {
 			// Document not in memory, retrieve from index
 			log.Printf("DEBUG: Retrieving document %s from index", hit.ID)
-			storedDoc, err := se.activeIndex.Document(hit.ID)
+			storedDoc, err := generation.index.Document(hit.ID)
 			if err != nil {
 				log.Printf("Warning: failed to retrieve document %s from index: %v", hit.ID, err)
 				continue
@@ -398,13 +391,7 @@ func (se *SearchEngine) Search(ctx context.Context, req *SearchRequest) (*Search
 				case "title":
 					doc.Title = string(field.Value())
 				case "content":
-					// Limit content to first 10KB to avoid buffer overflow
-					content := string(field.Value())
-					if len(content) > 10000 {
-						doc.Content = content[:10000] + "\n\n[Content truncated]"
-					} else {
-						doc.Content = content
-					}
+					doc.Content = string(field.Value())
 				case "version":
 					doc.Version = string(field.Value())
 				case "section":
@@ -428,15 +415,28 @@ func (se *SearchEngine) Search(ctx context.Context, req *SearchRequest) (*Search
 			continue
 		}
 
+		// Long pages are cut to one chunk; get_talos_document continues
+		// from the cursor
+		preview, cursor := documentPreview(doc)
+		backlinks := len(generation.backlinks[hit.ID])
 		result := &SearchResult{
-			Document: doc,
-			Score:    hit.Score,
-			Snippet:  se.extractSnippet(doc.Content),
-			Context:  se.extractContext(doc.Content),
+			Document:   preview,
+			Score:      hit.Score * linkRankBoost(backlinks),
+			Snippet:    se.extractSnippet(doc.Content),
+			Context:    se.extractContext(doc.Content),
+			Backlinks:  backlinks,
+			NextCursor: cursor,
 		}
 		results = append(results, result)
 	}
 
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
 	return &SearchResponse{
 		Results:  results,
 		Total:    int(searchResult.Total),
@@ -476,16 +476,53 @@ func (se *SearchEngine) extractContext(content string) string {
 }
 
 func (se *SearchEngine) GetDocument(id string) (*Document, bool) {
-	se.mu.RLock()
-	defer se.mu.RUnlock()
-	
-	doc, exists := se.documents[id]
+	doc, exists := se.current.Load().documents[id]
 	return doc, exists
 }
 
+// ListDocuments returns every indexed document, sorted by ID.
+func (se *SearchEngine) ListDocuments() []*Document {
+	generation := se.current.Load()
+
+	documents := make([]*Document, 0, len(generation.documents))
+	for _, doc := range generation.documents {
+		documents = append(documents, doc)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].ID < documents[j].ID
+	})
+	return documents
+}
+
+// GetDocumentByPath finds a document by its page path. With an empty version
+// the newest version containing the path wins.
+func (se *SearchEngine) GetDocumentByPath(pagePath, version string) (*Document, bool) {
+	generation := se.current.Load()
+
+	for _, id := range generation.paths[normalizePagePath(pagePath)] {
+		if doc := generation.documents[id]; version == "" || doc.Version == version {
+			return doc, true
+		}
+	}
+	return nil, false
+}
+
+// Backlinks returns the IDs of the documents linking to a document, sorted.
+func (se *SearchEngine) Backlinks(id string) []string {
+	return se.current.Load().backlinks[id]
+}
+
+// LookupDocument resolves a reference that is either a document ID or a page
+// path, so citations keep working whichever form an agent kept.
+func (se *SearchEngine) LookupDocument(ref, version string) (*Document, bool) {
+	if doc, exists := se.GetDocument(ref); exists {
+		return doc, true
+	}
+	return se.GetDocumentByPath(ref, version)
+}
+
 func (se *SearchEngine) GetTaxonomy() *ContentTaxonomy {
-	se.mu.RLock()
-	defer se.mu.RUnlock()
+	current := se.current.Load().taxonomy
 	
 	// Return a copy
 	taxonomy := &ContentTaxonomy{
@@ -495,16 +532,16 @@ func (se *SearchEngine) GetTaxonomy() *ContentTaxonomy {
 		Tags:      make(map[string]bool),
 	}
 	
-	for k, v := range se.taxonomy.Versions {
+	for k, v := range current.Versions {
 		taxonomy.Versions[k] = v
 	}
-	for k, v := range se.taxonomy.Sections {
+	for k, v := range current.Sections {
 		taxonomy.Sections[k] = v
 	}
-	for k, v := range se.taxonomy.Platforms {
+	for k, v := range current.Platforms {
 		taxonomy.Platforms[k] = v
 	}
-	for k, v := range se.taxonomy.Tags {
+	for k, v := range current.Tags {
 		taxonomy.Tags[k] = v
 	}
 	
@@ -512,25 +549,44 @@ func (se *SearchEngine) GetTaxonomy() *ContentTaxonomy {
 }
 
 func (se *SearchEngine) GetStats() map[string]interface{} {
-	se.mu.RLock()
-	defer se.mu.RUnlock()
+	generation := se.acquireCurrent()
+	defer generation.release()
 
 	stats := map[string]interface{}{
-		"total_documents": len(se.documents),
-		"versions":        len(se.taxonomy.Versions),
-		"sections":        len(se.taxonomy.Sections),
-		"platforms":       len(se.taxonomy.Platforms),
-		"tags":            len(se.taxonomy.Tags),
+		"generation":      generation.id,
+		"total_documents": len(generation.documents),
+		"versions":        len(generation.taxonomy.Versions),
+		"sections":        len(generation.taxonomy.Sections),
+		"platforms":       len(generation.taxonomy.Platforms),
+		"tags":            len(generation.taxonomy.Tags),
 	}
 
 	// Get index stats if available
-	if indexStats := se.activeIndex.Stats(); indexStats != nil {
+	if indexStats := generation.index.Stats(); indexStats != nil {
 		stats["index_stats"] = indexStats
 	}
 
 	return stats
 }
 
+// DocCount is the number of documents in the current generation's index.
+func (se *SearchEngine) DocCount() (uint64, error) {
+	generation := se.acquireCurrent()
+	defer generation.release()
+
+	return generation.index.DocCount()
+}
+
+// DocumentSchema is the document schema version the manifest records for
+// the current generation.
+func (se *SearchEngine) DocumentSchema() (int, error) {
+	manifest, err := se.readManifest()
+	if err != nil {
+		return 0, err
+	}
+	return manifest.DocumentSchema, nil
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
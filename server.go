@@ -7,6 +7,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -18,6 +20,26 @@ type TalosDocMCPServer struct {
 	fetcher     *DocumentationFetcher
 	searchEngine *SearchEngine
 	config      *Config
+
+	// apiKeys authenticate HTTP transport clients; empty disables auth
+	apiKeys []APIKey
+
+	// currentSync is the running sync job, lastSync the last finished one
+	syncMu      sync.Mutex
+	currentSync *SyncJob
+	lastSync    *SyncJob
+
+	// diagnostics is the report from the most recent extraction
+	diagnosticsMu sync.Mutex
+	diagnostics   *ExtractionReport
+
+	// resources maps each registered resource URI to what was registered
+	resourcesMu sync.Mutex
+	resources   map[string]registeredResource
+
+	// shutdownTransport stops the HTTP transport; nil for stdio
+	transportMu       sync.Mutex
+	shutdownTransport func(context.Context) error
 }
 
 func NewTalosDocMCPServer(config *Config) (*TalosDocMCPServer, error) {
@@ -29,8 +51,10 @@ func NewTalosDocMCPServer(config *Config) (*TalosDocMCPServer, error) {
 	// Initialize documentation fetcher
 	fetcher, err := NewDocumentationFetcher(
 		config.Repository.URL,
-		filepath.Join(os.TempDir(), "talos-docs-repo"),
+		defaultRepoPath(),
 		config.Repository.Branch,
+		config.Repository.HistoryDepth,
+		config.Repository.PublicURL,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize documentation fetcher: %w", err)
@@ -41,16 +65,38 @@ func NewTalosDocMCPServer(config *Config) (*TalosDocMCPServer, error) {
 		config.Search.IndexPath,
 		config.Search.MaxResults,
 		config.Search.SnippetLength,
+		config.Search.RetainedGenerations,
+		config.Search.MaxDocumentDrop,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize search engine: %w", err)
 	}
 
+	return newTalosDocMCPServer(config, fetcher, searchEngine)
+}
+
+// newTalosDocMCPServer sets up the MCP server, its tools, resources and
+// prompts around an existing fetcher and search engine.
+func newTalosDocMCPServer(config *Config, fetcher *DocumentationFetcher, searchEngine *SearchEngine) (*TalosDocMCPServer, error) {
+	apiKeys, err := loadAPIKeys(config.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API keys: %w", err)
+	}
+	if config.Server.Transport != transportStdio && len(apiKeys) == 0 && !config.Auth.AllowUnauthenticated {
+		return nil, fmt.Errorf("no API keys configured for the %s transport; add keys under auth.keys or auth.keys_file, or set auth.allow_unauthenticated to serve it without authentication", config.Server.Transport)
+	}
+
 	// Create MCP server with recovery middleware
 	mcpServer := server.NewMCPServer(
 		config.Server.Name,
 		config.Server.Version,
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, true), // Broadcast list_changed/updated, no subscriptions
+		server.WithPromptCapabilities(true),
+		server.WithPaginationLimit(resourcePageSize),
+		server.WithToolHandlerMiddleware(authorizeTool),
+		server.WithToolHandlerMiddleware(newToolLimits(config.Limits).middleware),
+		server.WithToolFilter(filterToolsByScope),
 		server.WithRecovery(), // Add panic recovery
 	)
 
@@ -59,6 +105,7 @@ func NewTalosDocMCPServer(config *Config) (*TalosDocMCPServer, error) {
 		fetcher:      fetcher,
 		searchEngine: searchEngine,
 		config:       config,
+		apiKeys:      apiKeys,
 	}
 
 	// Register tools
@@ -66,6 +113,13 @@ func NewTalosDocMCPServer(config *Config) (*TalosDocMCPServer, error) {
 		return nil, fmt.Errorf("failed to register tools: %w", err)
 	}
 
+	// Register resources for documents restored from the store
+	talosServer.registerResources()
+	talosServer.registerNavigationResources()
+	talosServer.refreshResources()
+
+	talosServer.registerPrompts()
+
 	return talosServer, nil
 }
 
@@ -112,10 +166,12 @@ func (s *TalosDocMCPServer) registerTools() error {
 
 	// Tool 3: compare_talos_versions
 	compareTool := mcp.NewTool("compare_talos_versions",
-		mcp.WithDescription("Compare documentation across different Talos versions"),
+		mcp.WithDescription("Compare documentation across different Talos versions: pages are matched by path and diffed section by section"),
 		mcp.WithString("topic",
-			mcp.Required(),
-			mcp.Description("Topic to compare"),
+			mcp.Description("Topic to compare; the pages matching it in either version are diffed"),
+		),
+		mcp.WithString("path",
+			mcp.Description("Compare a single page instead, given by path (with or without the version segment) or document ID"),
 		),
 		mcp.WithString("from_version",
 			mcp.Required(),
@@ -125,6 +181,10 @@ func (s *TalosDocMCPServer) registerTools() error {
 			mcp.Required(),
 			mcp.Description("Target version"),
 		),
+		mcp.WithString("format",
+			mcp.Description("sections (default) summarizes added, removed and changed sections; unified also includes a unified diff per page"),
+			mcp.Enum("sections", "unified"),
+		),
 	)
 
 	s.mcpServer.AddTool(compareTool, s.handleCompareVersions)
@@ -145,18 +205,195 @@ func (s *TalosDocMCPServer) registerTools() error {
 
 	// Tool 5: get_latest_release_notes
 	releaseTool := mcp.NewTool("get_latest_release_notes",
-		mcp.WithDescription("Get latest Talos release information"),
+		mcp.WithDescription("Get structured Talos release notes (version, component, change type and text) for a version or range of versions, parsed from the what's-new pages"),
+		mcp.WithString("version",
+			mcp.Description("Version to list changes for (default: latest)"),
+		),
+		mcp.WithString("from_version",
+			mcp.Description("Start of a version range, inclusive; use with to_version instead of version"),
+		),
+		mcp.WithString("to_version",
+			mcp.Description("End of a version range, inclusive (default: latest)"),
+		),
+		mcp.WithString("change_type",
+			mcp.Description("Only return changes of this type"),
+			mcp.Enum(changeFeature, changeDeprecation, changeBreaking, changeFix),
+		),
+		mcp.WithString("component",
+			mcp.Description("Only return changes whose component contains this text (e.g. kubernetes, networking)"),
+		),
 	)
 
 	s.mcpServer.AddTool(releaseTool, s.handleGetReleaseNotes)
 
 	// Tool 6: sync_documentation
 	syncTool := mcp.NewTool("sync_documentation",
-		mcp.WithDescription("Force sync with latest Talos documentation from GitHub"),
+		mcp.WithDescription("Start a background sync with the latest Talos documentation from GitHub; returns a job ID to check with get_sync_status"),
 	)
 
 	s.mcpServer.AddTool(syncTool, s.handleSyncDocumentation)
 
+	// Tool 7: get_index_diagnostics
+	diagnosticsTool := mcp.NewTool("get_index_diagnostics",
+		mcp.WithDescription("Report pages skipped during extraction: missing or empty files, duplicate IDs, invalid frontmatter, orphan files and navigation warnings"),
+		mcp.WithBoolean("refresh",
+			mcp.Description("Re-run extraction instead of returning the report from the last sync"),
+		),
+	)
+
+	s.mcpServer.AddTool(diagnosticsTool, s.handleGetIndexDiagnostics)
+
+	// Tool 8: get_sync_status
+	syncStatusTool := mcp.NewTool("get_sync_status",
+		mcp.WithDescription("Report the running and the last finished documentation sync: phase, duration, commit SHA, document count and errors"),
+	)
+
+	s.mcpServer.AddTool(syncStatusTool, s.handleGetSyncStatus)
+
+	// Tool 9: list_index_generations
+	generationsTool := mcp.NewTool("list_index_generations",
+		mcp.WithDescription("List the retained index generations with their commit SHA, document count, build time and which one is current"),
+	)
+
+	s.mcpServer.AddTool(generationsTool, s.handleListIndexGenerations)
+
+	// Tool 10: rollback_index
+	rollbackTool := mcp.NewTool("rollback_index",
+		mcp.WithDescription("Switch searches to a retained index generation"),
+		mcp.WithString("generation",
+			mcp.Description("Generation ID from list_index_generations (default: the newest generation older than the current one)"),
+		),
+	)
+
+	s.mcpServer.AddTool(rollbackTool, s.handleRollbackIndex)
+
+	// Tool 11: plan_talos_upgrade
+	upgradeTool := mcp.NewTool("plan_talos_upgrade",
+		mcp.WithDescription("Plan an upgrade across Talos versions: an ordered checklist per intermediate version of breaking changes, upgrade notes and new features, each citing its source page"),
+		mcp.WithString("from_version",
+			mcp.Required(),
+			mcp.Description("Version the cluster runs today (e.g. v1.8)"),
+		),
+		mcp.WithString("to_version",
+			mcp.Required(),
+			mcp.Description("Version to upgrade to (e.g. v1.11)"),
+		),
+	)
+
+	s.mcpServer.AddTool(upgradeTool, s.handlePlanUpgrade)
+
+	// Tool 12: lookup_machine_config_field
+	configFieldTool := mcp.NewTool("lookup_machine_config_field",
+		mcp.WithDescription("Look up machine configuration fields by path (e.g. machine.network.interfaces[].vip): type, default, allowed values, description and examples, plus the versions in which the field was added or removed"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Dotted field path; array indices may be written as [] or [0]"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Talos version to look the field up in (defaults to latest)"),
+		),
+		mcp.WithBoolean("prefix",
+			mcp.Description("Also return every field nested under path (default: false)"),
+		),
+	)
+
+	s.mcpServer.AddTool(configFieldTool, s.handleLookupConfigField)
+
+	// Tool 13: lookup_talosctl_command
+	talosctlTool := mcp.NewTool("lookup_talosctl_command",
+		mcp.WithDescription("Look up a talosctl command in the CLI reference for a Talos version: synopsis, usage, examples, flags (name, shorthand, type, default, description), parent and subcommands, and the flags that changed since another version"),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("Command name, with or without the talosctl prefix (e.g. 'apply-config', 'talosctl etcd snapshot')"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Talos version (defaults to latest)"),
+		),
+		mcp.WithString("compare_version",
+			mcp.Description("Version to diff flags against (defaults to the previous indexed version)"),
+		),
+	)
+
+	s.mcpServer.AddTool(talosctlTool, s.handleLookupTalosctlCommand)
+
+	// Tool 14: validate_machine_config
+	validateTool := mcp.NewTool("validate_machine_config",
+		mcp.WithDescription("Check a machine config or config patch (YAML, multiple documents allowed) against the configuration reference of a Talos version: unknown keys, type mismatches and deprecated fields, each linked to its documentation"),
+		mcp.WithString("config",
+			mcp.Required(),
+			mcp.Description("Machine config YAML"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Talos version to validate against (defaults to latest)"),
+		),
+	)
+
+	s.mcpServer.AddTool(validateTool, s.handleValidateMachineConfig)
+
+	// Tool 15: browse_talos_docs
+	browseTool := mcp.NewTool("browse_talos_docs",
+		mcp.WithDescription("Browse the documentation navigation tree of a Talos version, optionally rooted at a group (e.g. 'Networking'), with page titles, IDs and resource URIs"),
+		mcp.WithString("version",
+			mcp.Description("Talos version (defaults to latest)"),
+		),
+		mcp.WithString("group",
+			mcp.Description("Group to root the tree at; use 'Parent/Child' for a nested group whose name repeats"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Levels below the root to expand; deeper groups only report their page count (default: all)"),
+		),
+	)
+
+	s.mcpServer.AddTool(browseTool, s.handleBrowseDocs)
+
+	// Tool 16: get_talos_document
+	documentTool := mcp.NewTool("get_talos_document",
+		mcp.WithDescription("Read a specific documentation page by document ID, page path, talos-docs:// URI or docs URL, optionally just one section or a line or byte range. Long content comes in chunks; pass next_cursor back to continue"),
+		mcp.WithString("ref",
+			mcp.Description("Document ID (e.g. 'v1.11/talos/v1.11/networking/vip'), page path, talos-docs:// URI or docs URL; a URL's #anchor selects that section"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Version to pick when ref is a page path shared across versions (defaults to the newest)"),
+		),
+		mcp.WithString("section",
+			mcp.Description("Heading, heading trail ('Parent > Child') or anchor of the section to return, with its subsections"),
+		),
+		mcp.WithNumber("start_line",
+			mcp.Description("First line to return (1-based)"),
+		),
+		mcp.WithNumber("end_line",
+			mcp.Description("Last line to return (inclusive)"),
+		),
+		mcp.WithNumber("start_byte",
+			mcp.Description("First byte to return (0-based)"),
+		),
+		mcp.WithNumber("end_byte",
+			mcp.Description("Byte to stop before"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Content budget per response (default: 10000)"),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("next_cursor from a previous response or search result, to continue where it stopped; other arguments are ignored"),
+		),
+	)
+
+	s.mcpServer.AddTool(documentTool, s.handleGetDocument)
+
+	// Tool 17: get_related_docs
+	relatedTool := mcp.NewTool("get_related_docs",
+		mcp.WithDescription("List the documentation pages a page links to, the pages linking to it and its broken links, to follow cross-references without searching"),
+		mcp.WithString("ref",
+			mcp.Required(),
+			mcp.Description("Document ID (e.g. 'v1.11/talos/v1.11/networking/vip'), page path, talos-docs:// URI or docs URL"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Version to pick when ref is a page path shared across versions (defaults to the newest)"),
+		),
+	)
+
+	s.mcpServer.AddTool(relatedTool, s.handleGetRelatedDocs)
+
 	return nil
 }
 
@@ -243,6 +480,9 @@ func (s *TalosDocMCPServer) handleGetGuide(ctx context.Context, request mcp.Call
 		"version": version,
 		"platform": platform,
 	}
+	if cursor := response.Results[0].NextCursor; cursor != "" {
+		result["next_cursor"] = cursor
+	}
 
 	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -253,9 +493,10 @@ func (s *TalosDocMCPServer) handleGetGuide(ctx context.Context, request mcp.Call
 }
 
 func (s *TalosDocMCPServer) handleCompareVersions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	topic, err := request.RequireString("topic")
-	if err != nil {
-		return mcp.NewToolResultError("topic is required"), nil
+	topic := request.GetString("topic", "")
+	pagePath := request.GetString("path", "")
+	if topic == "" && pagePath == "" {
+		return mcp.NewToolResultError("topic or path is required"), nil
 	}
 
 	fromVersion, err := request.RequireString("from_version")
@@ -268,43 +509,66 @@ func (s *TalosDocMCPServer) handleCompareVersions(ctx context.Context, request m
 		return mcp.NewToolResultError("to_version is required"), nil
 	}
 
-	// Search for documents in both versions
-	searchReqFrom := &SearchRequest{
-		Query:   topic,
-		Version: fromVersion,
-		Limit:   5,
+	if fromVersion, err = s.resolveVersion(fromVersion); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if toVersion, err = s.resolveVersion(toVersion); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	searchReqTo := &SearchRequest{
-		Query:   topic,
-		Version: toVersion,
-		Limit:   5,
+	format := request.GetString("format", "sections")
+	if format != "sections" && format != "unified" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q (expected sections or unified)", format)), nil
 	}
 
-	responseFrom, err := s.searchEngine.Search(ctx, searchReqFrom)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("search for from_version failed: %v", err)), nil
+	// Match pages across versions by their path without the version segment
+	fromDocs := make(map[string]*Document)
+	toDocs := make(map[string]*Document)
+	for _, doc := range s.searchEngine.ListDocuments() {
+		switch doc.Version {
+		case fromVersion:
+			fromDocs[crossVersionPath(doc.Path, doc.Version)] = doc
+		case toVersion:
+			toDocs[crossVersionPath(doc.Path, doc.Version)] = doc
+		}
 	}
 
-	responseTo, err := s.searchEngine.Search(ctx, searchReqTo)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("search for to_version failed: %v", err)), nil
+	var paths []string
+	if pagePath != "" {
+		path := crossVersionPath(crossVersionPath(pagePath, fromVersion), toVersion)
+		if doc, exists := s.searchEngine.LookupDocument(pagePath, ""); exists {
+			path = crossVersionPath(doc.Path, doc.Version)
+		}
+		if fromDocs[path] == nil && toDocs[path] == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("no page at %s in %s or %s", pagePath, fromVersion, toVersion)), nil
+		}
+		paths = append(paths, path)
+	} else {
+		for _, version := range []string{fromVersion, toVersion} {
+			response, err := s.searchEngine.Search(ctx, &SearchRequest{
+				Query:   topic,
+				Version: version,
+				Limit:   compareSearchLimit,
+			})
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("search for %s failed: %v", version, err)), nil
+			}
+			for _, result := range response.Results {
+				paths = append(paths, crossVersionPath(result.Document.Path, result.Document.Version))
+			}
+		}
 	}
 
-	// Compare results
+	pages, summary := comparePages(paths, fromDocs, toDocs, format == "unified")
+
 	comparison := map[string]interface{}{
 		"topic":        topic,
-		"from_version": map[string]interface{}{
-			"version":  fromVersion,
-			"results":  responseFrom.Results,
-			"total":    responseFrom.Total,
-		},
-		"to_version": map[string]interface{}{
-			"version":  toVersion,
-			"results":  responseTo.Results,
-			"total":    responseTo.Total,
-		},
-		"changes": s.compareDocuments(responseFrom.Results, responseTo.Results),
+		"path":         pagePath,
+		"from_version": fromVersion,
+		"to_version":   toVersion,
+		"format":       format,
+		"summary":      summary,
+		"pages":        pages,
 	}
 
 	comparisonJSON, err := json.MarshalIndent(comparison, "", "  ")
@@ -352,27 +616,111 @@ func (s *TalosDocMCPServer) handleGetPlatformDocs(ctx context.Context, request m
 }
 
 func (s *TalosDocMCPServer) handleGetReleaseNotes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// Filter for latest version content
 	taxonomy := s.searchEngine.GetTaxonomy()
 	latestVersion := s.getLatestVersion(taxonomy.Versions)
 
-	// Search for release notes and what's new in the latest version
-	searchReq := &SearchRequest{
-		Query:   "what's new release notes",
-		Version: latestVersion,
-		Limit:   10,
+	version := request.GetString("version", "")
+	fromVersion := request.GetString("from_version", "")
+	toVersion := request.GetString("to_version", "")
+	if version != "" && (fromVersion != "" || toVersion != "") {
+		return mcp.NewToolResultError("use either version or from_version/to_version, not both"), nil
 	}
 
-	response, err := s.searchEngine.Search(ctx, searchReq)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+	// A single version is a range of one; both default to the latest
+	if version != "" {
+		fromVersion, toVersion = version, version
+	}
+	var err error
+	if toVersion, err = s.resolveVersion(toVersion); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if fromVersion == "" {
+		fromVersion = toVersion
+	} else if fromVersion, err = s.resolveVersion(fromVersion); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if compareVersions(fromVersion, toVersion) > 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("from_version %s is newer than to_version %s", fromVersion, toVersion)), nil
+	}
+
+	changeType := request.GetString("change_type", "")
+	switch changeType {
+	case "", changeFeature, changeDeprecation, changeBreaking, changeFix:
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unknown change_type %q (expected %s, %s, %s or %s)", changeType, changeFeature, changeDeprecation, changeBreaking, changeFix)), nil
+	}
+	component := strings.ToLower(request.GetString("component", ""))
+
+	type releaseNoteResult struct {
+		Version    string   `json:"version"`
+		Component  string   `json:"component"`
+		ChangeType string   `json:"change_type"`
+		Title      string   `json:"title"`
+		Text       string   `json:"text"`
+		Source     Citation `json:"source"`
+	}
+
+	var versions []string
+	for _, candidate := range s.getSortedVersions(taxonomy.Versions) {
+		if compareVersions(candidate, fromVersion) >= 0 && compareVersions(candidate, toVersion) <= 0 {
+			versions = append(versions, candidate)
+		}
+	}
+	inRange := make(map[string]bool, len(versions))
+	for _, candidate := range versions {
+		inRange[candidate] = true
+	}
+
+	// Collect per version, so notes come out oldest version first and in
+	// page order within a version
+	byVersion := make(map[string][]releaseNoteResult)
+	counts := make(map[string]int)
+	var missing []string
+	for _, doc := range s.searchEngine.ListDocuments() {
+		if !inRange[doc.Version] || !isReleaseNotesPage(doc) {
+			continue
+		}
+
+		for _, note := range doc.ReleaseNotes {
+			if changeType != "" && note.ChangeType != changeType {
+				continue
+			}
+			if component != "" && !strings.Contains(strings.ToLower(note.Component), component) {
+				continue
+			}
+			byVersion[doc.Version] = append(byVersion[doc.Version], releaseNoteResult{
+				Version:    note.Version,
+				Component:  note.Component,
+				ChangeType: note.ChangeType,
+				Title:      note.Title,
+				Text:       note.Text,
+				Source:     releaseNoteCitation(doc, note),
+			})
+			counts[note.ChangeType]++
+		}
+	}
+
+	releaseNotes := []releaseNoteResult{}
+	for _, candidate := range versions {
+		if len(byVersion[candidate]) == 0 {
+			missing = append(missing, candidate)
+		}
+		releaseNotes = append(releaseNotes, byVersion[candidate]...)
 	}
 
 	result := map[string]interface{}{
 		"latest_version": latestVersion,
-		"release_notes":  response.Results,
+		"versions":       versions,
+		"change_type":    changeType,
+		"component":      component,
+		"total":          len(releaseNotes),
+		"counts":         counts,
+		"release_notes":  releaseNotes,
 		"all_versions":   s.getSortedVersions(taxonomy.Versions),
 	}
+	if len(missing) > 0 {
+		result["versions_without_notes"] = missing
+	}
 
 	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -385,33 +733,79 @@ func (s *TalosDocMCPServer) handleGetReleaseNotes(ctx context.Context, request m
 func (s *TalosDocMCPServer) handleSyncDocumentation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	log.Printf("Manual documentation sync requested")
 
-	// Try to pull latest changes
-	if err := s.fetcher.ForceSync(); err != nil {
-		log.Printf("Failed to sync repository: %v", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to sync repository: %v", err)), nil
+	// Only one extract and reindex at a time; concurrent requests are told
+	// about the running one instead of queuing another rebuild
+	job, ok := s.beginSync("manual")
+	if !ok {
+		log.Printf("Sync %s already in progress since %s, rejecting request", job.ID, job.StartedAt.Format(time.RFC3339))
+		return mcp.NewToolResultError(fmt.Sprintf("sync already in progress, started at %s (job %s)", job.StartedAt.Format(time.RFC3339), job.ID)), nil
 	}
 
-	// Reload navigation and documents
-	nav, err := s.fetcher.GetNavigation()
+	// The full cycle can outlast client timeouts, so it runs in the
+	// background and reports through progress notifications and
+	// get_sync_status
+	notify := s.progressNotifier(ctx, request)
+	go func() {
+		// Outside the handler, server.WithRecovery no longer catches a
+		// panic; without this it would take the server down and leave the
+		// job running forever
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Sync %s panicked: %v", job.ID, r)
+				notify.report("failed: %v", r)
+				s.finishSync(fmt.Errorf("sync panicked: %v", r))
+			}
+		}()
+
+		err := s.runSync(true, notify)
+		if err != nil {
+			log.Printf("Sync %s failed: %v", job.ID, err)
+			notify.report("failed: %v", err)
+		} else {
+			log.Printf("Sync %s finished", job.ID)
+			notify.report("done")
+		}
+		s.finishSync(err)
+	}()
+
+	result := map[string]interface{}{
+		"status":     syncStatusRunning,
+		"job_id":     job.ID,
+		"started_at": job.StartedAt.Format(time.RFC3339),
+		"message":    "Sync started; check get_sync_status for progress and the result",
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get navigation: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal result: %v", err)), nil
 	}
 
-	documents, err := s.fetcher.ExtractDocuments(nav)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func (s *TalosDocMCPServer) handleGetIndexDiagnostics(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	report, err := s.getDiagnostics(request.GetBool("refresh", false))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to extract documents: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to build diagnostics: %v", err)), nil
 	}
 
-	// Reindex
-	if err := s.searchEngine.IndexDocuments(documents); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to reindex documents: %v", err)), nil
+	result := map[string]interface{}{
+		"total_issues": report.IssueCount(),
+		"summary":      report.Summary(),
+		"report":       report,
 	}
 
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func (s *TalosDocMCPServer) handleListIndexGenerations(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	result := map[string]interface{}{
-		"status":    "success",
-		"message":   "Documentation synced successfully",
-		"documents": len(documents),
-		"timestamp": time.Now().Format(time.RFC3339),
+		"generations": s.searchEngine.ListGenerations(),
 	}
 
 	resultJSON, err := json.MarshalIndent(result, "", "  ")
@@ -422,34 +816,61 @@ func (s *TalosDocMCPServer) handleSyncDocumentation(ctx context.Context, request
 	return mcp.NewToolResultText(string(resultJSON)), nil
 }
 
-func (s *TalosDocMCPServer) compareDocuments(fromResults, toResults []*SearchResult) []string {
-	var changes []string
+func (s *TalosDocMCPServer) handleRollbackIndex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// A running sync would publish over the rollback as soon as it finishes
+	if current, _ := s.syncStatus(); current != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("sync %s is in progress, retry once it finishes", current.ID)), nil
+	}
 
-	fromTitles := make(map[string]bool)
-	for _, result := range fromResults {
-		fromTitles[result.Document.Title] = true
+	info, err := s.searchEngine.Rollback(request.GetString("generation", ""))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to roll back index: %v", err)), nil
 	}
+	s.refreshResources()
 
-	toTitles := make(map[string]bool)
-	for _, result := range toResults {
-		toTitles[result.Document.Title] = true
+	result := map[string]interface{}{
+		"status":     "success",
+		"generation": info,
 	}
 
-	// Find new content
-	for title := range toTitles {
-		if !fromTitles[title] {
-			changes = append(changes, fmt.Sprintf("NEW: %s", title))
-		}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal result: %v", err)), nil
 	}
 
-	// Find removed content
-	for title := range fromTitles {
-		if !toTitles[title] {
-			changes = append(changes, fmt.Sprintf("REMOVED: %s", title))
-		}
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func (s *TalosDocMCPServer) setDiagnostics(report *ExtractionReport) {
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	s.diagnostics = report
+}
+
+// getDiagnostics returns the last extraction report. When the index was
+// reused at startup nothing has been extracted yet, so extraction runs once
+// on demand; it only reads the checkout and doesn't touch the index.
+func (s *TalosDocMCPServer) getDiagnostics(refresh bool) (*ExtractionReport, error) {
+	s.diagnosticsMu.Lock()
+	report := s.diagnostics
+	s.diagnosticsMu.Unlock()
+
+	if report != nil && !refresh {
+		return report, nil
 	}
 
-	return changes
+	nav, err := s.fetcher.GetNavigation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get navigation: %w", err)
+	}
+
+	_, report, err = s.fetcher.ExtractDocuments(nav, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract documents: %w", err)
+	}
+
+	s.setDiagnostics(report)
+	return report, nil
 }
 
 func (s *TalosDocMCPServer) getLatestVersion(versions map[string]bool) string {
@@ -458,27 +879,43 @@ func (s *TalosDocMCPServer) getLatestVersion(versions map[string]bool) string {
 	}
 	latest := ""
 	for version := range versions {
-		if version > latest {
+		if latest == "" || compareVersions(version, latest) > 0 {
 			latest = version
 		}
 	}
 	return latest
 }
 
+// resolveVersion checks a requested version against the index, defaulting
+// to the latest one when empty.
+func (s *TalosDocMCPServer) resolveVersion(version string) (string, error) {
+	versions := s.searchEngine.GetTaxonomy().Versions
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return s.getLatestVersion(versions), nil
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !versions[version] {
+		available := s.getSortedVersions(versions)
+		return "", fmt.Errorf("version %s is not indexed (available: %s)", version, strings.Join(available, ", "))
+	}
+	return version, nil
+}
+
 func (s *TalosDocMCPServer) getSortedVersions(versions map[string]bool) []string {
 	var sorted []string
 	for version := range versions {
 		sorted = append(sorted, version)
 	}
-	// Simple version sorting (would need more sophisticated for real semantic versions)
+	sortVersions(sorted)
 	return sorted
 }
 
 func (s *TalosDocMCPServer) Start() error {
 	// Start serving MCP immediately - don't block on index initialization
 	// The index will be initialized in the background after MCP handshake completes
-	log.Printf("==> Starting MCP Server on stdio...")
-
 	// Initialize documents in background (after server starts listening)
 	go func() {
 		log.Printf("Initializing documentation index in background...")
@@ -490,44 +927,51 @@ func (s *TalosDocMCPServer) Start() error {
 		}
 	}()
 
-	return server.ServeStdio(s.mcpServer)
+	return s.serve()
 }
 
 func (s *TalosDocMCPServer) initializeDocuments() error {
-	// Check if index already has documents
-	docCount, err := s.searchEngine.activeIndex.DocCount()
+	// Check if index already has documents extracted the way this version
+	// extracts them
+	docCount, err := s.searchEngine.DocCount()
 	if err == nil && docCount > 0 {
-		log.Printf("Using existing index with %d documents (skipping rebuild)", docCount)
+		schema, err := s.searchEngine.DocumentSchema()
+		if err == nil && schema == documentSchemaVersion {
+			log.Printf("Using existing index with %d documents (skipping rebuild)", docCount)
+			return nil
+		}
+		log.Printf("Existing index uses document schema %d, rebuilding for schema %d", schema, documentSchemaVersion)
+	}
+
+	// No existing documents or outdated ones, need to build index
+	job, ok := s.beginSync("startup")
+	if !ok {
+		log.Printf("Sync %s already in progress, skipping initial build", job.ID)
 		return nil
 	}
 
-	// No existing documents, need to build index
 	log.Printf("Building fresh index...")
 
-	// Get navigation and extract documents
-	nav, err := s.fetcher.GetNavigation()
-	if err != nil {
-		return fmt.Errorf("failed to get navigation: %w", err)
-	}
-
-	documents, err := s.fetcher.ExtractDocuments(nav)
+	// The fetcher cloned or pulled the repository when it was created
+	err = s.runSync(false, nil)
+	s.finishSync(err)
 	if err != nil {
-		return fmt.Errorf("failed to extract documents: %w", err)
-	}
-
-	if len(documents) == 0 {
-		return fmt.Errorf("no documents found")
-	}
-
-	// Index documents
-	if err := s.searchEngine.IndexDocuments(documents); err != nil {
-		return fmt.Errorf("failed to index documents: %w", err)
+		return err
 	}
 
-	log.Printf("Initialized with %d documents", len(documents))
+	_, last := s.syncStatus()
+	log.Printf("Initialized with %d documents", last.Documents)
 	return nil
 }
 
 func (s *TalosDocMCPServer) Stop() {
-	s.fetcher.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down transport cleanly: %v", err)
+	}
+
+	if s.fetcher != nil {
+		s.fetcher.Stop()
+	}
 }
\ No newline at end of file
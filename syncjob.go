@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	syncStatusRunning   = "running"
+	syncStatusSucceeded = "succeeded"
+	syncStatusFailed    = "failed"
+)
+
+// ProgressFunc receives human-readable progress from long-running steps
+// such as extraction and indexing. A nil ProgressFunc discards it.
+type ProgressFunc func(message string)
+
+func (p ProgressFunc) report(format string, args ...interface{}) {
+	if p != nil {
+		p(fmt.Sprintf(format, args...))
+	}
+}
+
+// SyncJob is one fetch, extract and reindex run.
+type SyncJob struct {
+	ID         string         `json:"id"`
+	Trigger    string         `json:"trigger"`
+	Status     string         `json:"status"`
+	Phase      string         `json:"phase"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Duration   string         `json:"duration"`
+	CommitSHA  string         `json:"commit_sha,omitempty"`
+	Documents  int            `json:"documents,omitempty"`
+	Issues     map[string]int `json:"issues,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// beginSync starts a new job unless one is already running, in which case
+// it returns a snapshot of the running job and false.
+func (s *TalosDocMCPServer) beginSync(trigger string) (*SyncJob, bool) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if s.currentSync != nil {
+		return s.currentSync.snapshot(), false
+	}
+
+	s.currentSync = &SyncJob{
+		ID:        newSyncJobID(),
+		Trigger:   trigger,
+		Status:    syncStatusRunning,
+		Phase:     "starting",
+		StartedAt: time.Now(),
+	}
+	return s.currentSync.snapshot(), true
+}
+
+// updateSync applies update to the running job under the lock.
+func (s *TalosDocMCPServer) updateSync(update func(job *SyncJob)) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if s.currentSync != nil {
+		update(s.currentSync)
+	}
+}
+
+// finishSync records the outcome of the running job and moves it to last.
+func (s *TalosDocMCPServer) finishSync(err error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	job := s.currentSync
+	if job == nil {
+		return
+	}
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = syncStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = syncStatusSucceeded
+		job.Phase = "done"
+	}
+
+	s.lastSync = job
+	s.currentSync = nil
+}
+
+// syncStatus returns snapshots of the running and the last finished job;
+// either may be nil.
+func (s *TalosDocMCPServer) syncStatus() (current, last *SyncJob) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if s.currentSync != nil {
+		current = s.currentSync.snapshot()
+	}
+	if s.lastSync != nil {
+		last = s.lastSync.snapshot()
+	}
+	return current, last
+}
+
+func (j *SyncJob) snapshot() *SyncJob {
+	copied := *j
+	end := time.Now()
+	if j.FinishedAt != nil {
+		end = *j.FinishedAt
+	}
+	copied.Duration = end.Sub(j.StartedAt).Round(time.Millisecond).String()
+	return &copied
+}
+
+// runSync performs the job: optionally pull the repository, then extract,
+// reindex and refresh resources. Each phase is recorded on the job and
+// passed to notify.
+func (s *TalosDocMCPServer) runSync(pull bool, notify ProgressFunc) error {
+	progress := ProgressFunc(func(message string) {
+		s.updateSync(func(job *SyncJob) { job.Phase = message })
+		notify.report("%s", message)
+	})
+
+	if pull {
+		progress.report("fetching")
+		if err := s.fetcher.ForceSync(); err != nil {
+			return fmt.Errorf("failed to sync repository: %w", err)
+		}
+	}
+
+	commitSHA, err := s.fetcher.HeadCommit()
+	if err != nil {
+		log.Printf("Warning: failed to resolve source commit: %v", err)
+	} else {
+		s.updateSync(func(job *SyncJob) { job.CommitSHA = commitSHA })
+	}
+
+	nav, err := s.fetcher.GetNavigation()
+	if err != nil {
+		return fmt.Errorf("failed to get navigation: %w", err)
+	}
+
+	documents, report, err := s.fetcher.ExtractDocuments(nav, progress)
+	if err != nil {
+		return fmt.Errorf("failed to extract documents: %w", err)
+	}
+	s.setDiagnostics(report)
+	s.updateSync(func(job *SyncJob) {
+		job.Documents = len(documents)
+		job.Issues = report.Summary()
+	})
+
+	if len(documents) == 0 {
+		return fmt.Errorf("no documents found")
+	}
+
+	if err := s.searchEngine.IndexDocuments(documents, commitSHA, progress); err != nil {
+		return fmt.Errorf("failed to reindex documents: %w", err)
+	}
+	s.refreshResources()
+
+	return nil
+}
+
+// progressNotifier sends MCP progress notifications for the calling
+// request, if the client asked for them with a progress token.
+// Notifications are addressed by session ID rather than through the request
+// context: they keep coming after the tool call returns, and the streamable
+// HTTP transport delivers those over the session's standing GET stream, not
+// the finished request.
+func (s *TalosDocMCPServer) progressNotifier(ctx context.Context, request mcp.CallToolRequest) ProgressFunc {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil
+	}
+
+	sessionID := session.SessionID()
+	token := request.Params.Meta.ProgressToken
+	step := 0
+	return func(message string) {
+		step++
+		err := s.mcpServer.SendNotificationToSpecificClient(sessionID, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      step,
+			"message":       message,
+		})
+		if err != nil {
+			log.Printf("Failed to send sync progress to session %s: %v", sessionID, err)
+		}
+	}
+}
+
+func (s *TalosDocMCPServer) handleGetSyncStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	current, last := s.syncStatus()
+
+	result := map[string]interface{}{
+		"current": current,
+		"last":    last,
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func newSyncJobID() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("sync-%d", time.Now().UnixNano())
+	}
+	return "sync-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newSyncTestServer serves an empty index; fetcher may be nil for tests
+// that never reach it.
+func newSyncTestServer(t *testing.T, fetcher *DocumentationFetcher) *TalosDocMCPServer {
+	t.Helper()
+	searchEngine := openTestEngine(t, t.TempDir())
+	t.Cleanup(func() { searchEngine.Close() })
+
+	config := &Config{}
+	config.Server.Transport = transportStdio
+	s, err := newTalosDocMCPServer(config, fetcher, searchEngine)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return s
+}
+
+func TestSyncJobLifecycle(t *testing.T) {
+	s := newSyncTestServer(t, nil)
+
+	if current, last := s.syncStatus(); current != nil || last != nil {
+		t.Fatalf("new server reports jobs %+v and %+v", current, last)
+	}
+
+	job, ok := s.beginSync("manual")
+	if !ok || job.Status != syncStatusRunning || job.Trigger != "manual" || !strings.HasPrefix(job.ID, "sync-") {
+		t.Fatalf("beginSync() = %+v, %v", job, ok)
+	}
+	running, ok := s.beginSync("startup")
+	if ok || running.ID != job.ID {
+		t.Errorf("second beginSync() = %s, %v, want the running job %s and false", running.ID, ok, job.ID)
+	}
+
+	s.updateSync(func(job *SyncJob) { job.Phase = "indexing" })
+	current, _ := s.syncStatus()
+	if current == nil || current.Phase != "indexing" || current.FinishedAt != nil {
+		t.Errorf("running job %+v, want phase indexing and not finished", current)
+	}
+	// Snapshots don't share state with the job
+	current.Phase = "changed"
+	if again, _ := s.syncStatus(); again.Phase != "indexing" {
+		t.Errorf("changing a snapshot changed the job phase to %q", again.Phase)
+	}
+
+	s.finishSync(errors.New("failed to get navigation"))
+	current, last := s.syncStatus()
+	if current != nil {
+		t.Errorf("finished job still running: %+v", current)
+	}
+	if last == nil || last.ID != job.ID || last.Status != syncStatusFailed || last.Error != "failed to get navigation" || last.Phase != "indexing" || last.FinishedAt == nil {
+		t.Errorf("failed job recorded as %+v", last)
+	}
+
+	next, ok := s.beginSync("manual")
+	if !ok || next.ID == job.ID {
+		t.Fatalf("beginSync() after a failure = %+v, %v, want a new job", next, ok)
+	}
+	s.finishSync(nil)
+	_, last = s.syncStatus()
+	if last.ID != next.ID || last.Status != syncStatusSucceeded || last.Phase != "done" || last.Error != "" {
+		t.Errorf("succeeded job recorded as %+v", last)
+	}
+	finished := last.Duration
+	time.Sleep(5 * time.Millisecond)
+	if _, last = s.syncStatus(); last.Duration != finished {
+		t.Errorf("finished job duration moved from %s to %s", finished, last.Duration)
+	}
+}
+
+func TestSyncDocumentationRejectsConcurrentSync(t *testing.T) {
+	s := newSyncTestServer(t, nil)
+	job, _ := s.beginSync("startup")
+
+	result, err := s.handleSyncDocumentation(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("handleSyncDocumentation() error = %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "sync already in progress") || !strings.Contains(text, job.ID) {
+		t.Errorf("concurrent sync returned %q (error %v), want a rejection naming %s", text, result.IsError, job.ID)
+	}
+	if current, _ := s.syncStatus(); current == nil || current.ID != job.ID {
+		t.Errorf("running job replaced by %+v", current)
+	}
+}
+
+func TestSyncDocumentationRecoversFromPanic(t *testing.T) {
+	// Without a fetcher the background sync panics on its first step
+	s := newSyncTestServer(t, nil)
+
+	result, err := s.handleSyncDocumentation(context.Background(), mcp.CallToolRequest{})
+	if err != nil || result.IsError {
+		t.Fatalf("handleSyncDocumentation() = %+v, %v", result, err)
+	}
+
+	var last *SyncJob
+	deadline := time.Now().Add(5 * time.Second)
+	for last == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, last = s.syncStatus()
+	}
+	if last == nil {
+		t.Fatal("panicking sync never finished")
+	}
+	if last.Status != syncStatusFailed || !strings.HasPrefix(last.Error, "sync panicked:") {
+		t.Errorf("panicking sync recorded as %+v", last)
+	}
+	if _, ok := s.beginSync("manual"); !ok {
+		t.Error("a new sync can't start after the panic")
+	}
+}
+
+func TestRunSync(t *testing.T) {
+	checkout := t.TempDir()
+	public := filepath.Join(checkout, "public")
+	files := map[string]string{
+		"docs.json": `{"navigation": {"tabs": [{"tab": "Talos", "versions": [{"version": "v1.11", "groups": [
+			{"group": "Networking", "pages": ["talos/v1.11/networking/vip", "talos/v1.11/networking/missing"]}
+		]}]}]}}`,
+		"talos/v1.11/networking/vip.mdx": "# Virtual IP\n\nShare an IP between control plane nodes.\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(public, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newSyncTestServer(t, &DocumentationFetcher{localPath: checkout, publicURL: defaultPublicURL})
+
+	s.beginSync("manual")
+	var phases []string
+	err := s.runSync(false, func(message string) { phases = append(phases, message) })
+	current, _ := s.syncStatus()
+	s.finishSync(err)
+	if err != nil {
+		t.Fatalf("runSync() error = %v", err)
+	}
+
+	if current.Documents != 1 || current.Issues["missing_files"] != 1 {
+		t.Errorf("job counted %d documents and issues %v, want 1 document and 1 missing file", current.Documents, current.Issues)
+	}
+	if current.Phase != phases[len(phases)-1] {
+		t.Errorf("job phase %q isn't the last progress message %q", current.Phase, phases[len(phases)-1])
+	}
+	if !strings.Contains(strings.Join(phases, "\n"), "extracting version v1.11") {
+		t.Errorf("progress %v doesn't report extraction", phases)
+	}
+	if _, exists := s.searchEngine.GetDocument("v1.11/talos/v1.11/networking/vip"); !exists {
+		t.Error("synced page isn't indexed")
+	}
+	if report, _ := s.getDiagnostics(false); report == nil || len(report.MissingFiles) != 1 {
+		t.Errorf("diagnostics not recorded from the sync: %+v", report)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	flagAdded   = "added"
+	flagRemoved = "removed"
+	flagChanged = "changed"
+)
+
+// flagDefaultPattern matches the "(default ...)" cobra appends to a flag's
+// usage.
+var flagDefaultPattern = regexp.MustCompile(`\s*\(default (.*)\)$`)
+
+// TalosctlFlag is one flag of a talosctl command.
+type TalosctlFlag struct {
+	Name        string `json:"name"`
+	Shorthand   string `json:"shorthand,omitempty"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description"`
+}
+
+// TalosctlCommand is one command of the talosctl CLI reference.
+type TalosctlCommand struct {
+	Name           string         `json:"name"`
+	Short          string         `json:"short,omitempty"`
+	Synopsis       string         `json:"synopsis,omitempty"`
+	Usage          string         `json:"usage,omitempty"`
+	Examples       string         `json:"examples,omitempty"`
+	Flags          []TalosctlFlag `json:"flags"`
+	InheritedFlags []TalosctlFlag `json:"inherited_flags,omitempty"`
+	Parent         string         `json:"parent,omitempty"`
+	Subcommands    []string       `json:"subcommands,omitempty"`
+	Version        string         `json:"version"`
+	StartLine      int            `json:"start_line"`
+	EndLine        int            `json:"end_line"`
+}
+
+// FlagChange is a flag that differs between two versions of a command.
+type FlagChange struct {
+	Flag   string        `json:"flag"`
+	Change string        `json:"change"`
+	From   *TalosctlFlag `json:"from,omitempty"`
+	To     *TalosctlFlag `json:"to,omitempty"`
+}
+
+// isCLIReferencePage recognises the talosctl CLI reference.
+func isCLIReferencePage(doc *Document) bool {
+	return strings.Contains(doc.Path, "reference/cli")
+}
+
+// parseTalosctlCommands reads the cobra-generated CLI reference: every
+// "talosctl ..." heading starts a command, and the Synopsis, Examples and
+// Options headings below it fill it in. Parents and subcommands follow
+// from the command names.
+func parseTalosctlCommands(doc *Document) []TalosctlCommand {
+	var commands []TalosctlCommand
+	var current *TalosctlCommand
+	for _, section := range splitSections(doc.Content) {
+		heading := strings.Trim(section.Heading, "` ")
+		if heading == "talosctl" || strings.HasPrefix(heading, "talosctl ") {
+			commands = append(commands, TalosctlCommand{
+				Name:      strings.Join(strings.Fields(heading), " "),
+				Short:     sectionSummary(section.Body, upgradeSummaryLength),
+				Flags:     []TalosctlFlag{},
+				Version:   doc.Version,
+				StartLine: section.StartLine,
+			})
+			current = &commands[len(commands)-1]
+			current.EndLine = section.EndLine
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		current.EndLine = section.EndLine
+
+		blocks := codeBlocks(section.Body, "")
+		switch name := strings.ToLower(section.Heading); {
+		case name == "synopsis":
+			current.Synopsis = sectionSummary(section.Body, len(section.Body))
+			if len(blocks) > 0 {
+				current.Usage = strings.TrimSpace(blocks[0])
+			}
+		case name == "examples":
+			current.Examples = strings.TrimSpace(strings.Join(blocks, "\n\n"))
+		case strings.HasPrefix(name, "options inherited"):
+			for _, block := range blocks {
+				current.InheritedFlags = append(current.InheritedFlags, parseFlags(block)...)
+			}
+		case name == "options":
+			for _, block := range blocks {
+				current.Flags = append(current.Flags, parseFlags(block)...)
+			}
+		}
+	}
+
+	names := make(map[string]bool, len(commands))
+	for _, command := range commands {
+		names[command.Name] = true
+	}
+	for i := range commands {
+		words := strings.Fields(commands[i].Name)
+		if len(words) > 1 && names[strings.Join(words[:len(words)-1], " ")] {
+			commands[i].Parent = strings.Join(words[:len(words)-1], " ")
+		}
+	}
+	for i := range commands {
+		for _, command := range commands {
+			if command.Parent == commands[i].Name {
+				commands[i].Subcommands = append(commands[i].Subcommands, command.Name)
+			}
+		}
+	}
+	return commands
+}
+
+// parseFlags reads a cobra flag listing, one flag per line:
+//
+//	-m, --mode auto, interactive, reboot   apply config mode (default auto)
+//	    --dry-run                          check how the config change will be applied
+//
+// The type and usage are separated by a run of spaces; flags without a
+// type are booleans.
+func parseFlags(block string) []TalosctlFlag {
+	var flags []TalosctlFlag
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		flag := TalosctlFlag{}
+		if len(line) > 4 && line[0] == '-' && line[1] != '-' && line[2] == ',' {
+			flag.Shorthand = line[1:2]
+			line = strings.TrimSpace(line[3:])
+		}
+		if !strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line[2:], " ")
+		flag.Name = name
+		if typ, description, found := strings.Cut(rest, "  "); found {
+			flag.Type = strings.TrimSpace(typ)
+			flag.Description = strings.TrimSpace(description)
+		} else {
+			flag.Description = strings.TrimSpace(rest)
+		}
+		if flag.Type == "" {
+			flag.Type = "bool"
+		}
+		if match := flagDefaultPattern.FindStringSubmatch(flag.Description); match != nil {
+			flag.Default = strings.Trim(match[1], `"`)
+			flag.Description = strings.TrimSpace(flag.Description[:len(flag.Description)-len(match[0])])
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// talosctlCommandName normalizes a command name, so "apply-config" and
+// "talosctl  apply-config" both name talosctl apply-config.
+func talosctlCommandName(name string) string {
+	words := strings.Fields(strings.ToLower(name))
+	if len(words) == 0 || words[0] != "talosctl" {
+		words = append([]string{"talosctl"}, words...)
+	}
+	return strings.Join(words, " ")
+}
+
+// talosctlCommands returns the commands of every CLI reference page keyed
+// by version and then by command name, along with the page documenting
+// each, keyed by version and command name.
+func (s *TalosDocMCPServer) talosctlCommands() (map[string]map[string]TalosctlCommand, map[string]*Document) {
+	byVersion := make(map[string]map[string]TalosctlCommand)
+	pages := make(map[string]*Document)
+	for _, doc := range s.searchEngine.ListDocuments() {
+		if !isCLIReferencePage(doc) {
+			continue
+		}
+		if len(doc.TalosctlCommands) == 0 {
+			continue
+		}
+		if byVersion[doc.Version] == nil {
+			byVersion[doc.Version] = make(map[string]TalosctlCommand)
+		}
+		for _, command := range doc.TalosctlCommands {
+			byVersion[doc.Version][command.Name] = command
+			pages[doc.Version+"/"+command.Name] = doc
+		}
+	}
+	return byVersion, pages
+}
+
+// diffFlags lists the flags added, removed or changed in type, shorthand
+// or default between two versions of a command.
+func diffFlags(from, to []TalosctlFlag) []FlagChange {
+	fromFlags := make(map[string]*TalosctlFlag, len(from))
+	for i := range from {
+		fromFlags[from[i].Name] = &from[i]
+	}
+	toFlags := make(map[string]*TalosctlFlag, len(to))
+	for i := range to {
+		toFlags[to[i].Name] = &to[i]
+	}
+
+	changes := []FlagChange{}
+	for i := range to {
+		old, ok := fromFlags[to[i].Name]
+		switch {
+		case !ok:
+			changes = append(changes, FlagChange{Flag: "--" + to[i].Name, Change: flagAdded, To: &to[i]})
+		case old.Type != to[i].Type || old.Shorthand != to[i].Shorthand || old.Default != to[i].Default:
+			changes = append(changes, FlagChange{Flag: "--" + to[i].Name, Change: flagChanged, From: old, To: &to[i]})
+		}
+	}
+	for i := range from {
+		if _, ok := toFlags[from[i].Name]; !ok {
+			changes = append(changes, FlagChange{Flag: "--" + from[i].Name, Change: flagRemoved, From: &from[i]})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Flag < changes[j].Flag
+	})
+	return changes
+}
+
+func (s *TalosDocMCPServer) handleLookupTalosctlCommand(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	command, err := request.RequireString("command")
+	if err != nil {
+		return mcp.NewToolResultError("command is required"), nil
+	}
+	version, err := s.resolveVersion(request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	byVersion, pages := s.talosctlCommands()
+	if len(byVersion[version]) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no talosctl CLI reference is indexed for %s", version)), nil
+	}
+
+	var referenceVersions []string
+	for v := range byVersion {
+		referenceVersions = append(referenceVersions, v)
+	}
+	sortVersions(referenceVersions)
+
+	// Compare against the previous version with a CLI reference unless
+	// told otherwise
+	compareVersion := ""
+	if requested := request.GetString("compare_version", ""); requested != "" {
+		if compareVersion, err = s.resolveVersion(requested); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("compare_version: %v", err)), nil
+		}
+		if len(byVersion[compareVersion]) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no talosctl CLI reference is indexed for %s", compareVersion)), nil
+		}
+	} else {
+		for _, v := range referenceVersions {
+			if compareVersions(v, version) < 0 {
+				compareVersion = v
+			}
+		}
+	}
+
+	name := talosctlCommandName(command)
+	availability := []string{}
+	for _, v := range referenceVersions {
+		if _, ok := byVersion[v][name]; ok {
+			availability = append(availability, v)
+		}
+	}
+
+	result := map[string]interface{}{
+		"command":      name,
+		"version":      version,
+		"versions":     availability,
+		"all_versions": referenceVersions,
+	}
+
+	found, ok := byVersion[version][name]
+	if !ok {
+		// Point at the commands the caller may have meant
+		suggestions := []string{}
+		last := strings.Fields(name)[len(strings.Fields(name))-1]
+		for candidate := range byVersion[version] {
+			if strings.Contains(candidate, last) {
+				suggestions = append(suggestions, candidate)
+			}
+		}
+		sort.Strings(suggestions)
+		result["suggestions"] = suggestions
+		if len(availability) > 0 {
+			result["message"] = fmt.Sprintf("%s is not documented in %s; it is documented in %s", name, version, strings.Join(availability, ", "))
+		} else {
+			result["message"] = fmt.Sprintf("%s is not documented in any indexed version", name)
+		}
+	} else {
+		result["result"] = found
+		doc := pages[version+"/"+name]
+		result["source"] = Citation{
+			DocumentID: doc.ID,
+			Title:      doc.Title,
+			URI:        documentResourceURI(doc),
+			URL:        sectionURL(doc, found.Name),
+			Section:    found.Name,
+			Lines:      fmt.Sprintf("%d-%d", found.StartLine, found.EndLine),
+		}
+
+		if compareVersion != "" {
+			// Describe changes going from the older version to the newer
+			fromVersion, toVersion := compareVersion, version
+			if compareVersions(compareVersion, version) > 0 {
+				fromVersion, toVersion = version, compareVersion
+			}
+			differences := map[string]interface{}{
+				"from_version": fromVersion,
+				"to_version":   toVersion,
+			}
+			if _, ok := byVersion[compareVersion][name]; ok {
+				differences["flags"] = diffFlags(byVersion[fromVersion][name].Flags, byVersion[toVersion][name].Flags)
+			} else {
+				differences["message"] = fmt.Sprintf("%s is not documented in %s", name, compareVersion)
+			}
+			result["differences"] = differences
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want TalosctlFlag
+	}{
+		{
+			name: "shorthand with enumerated type and default",
+			line: "  -m, --mode auto, interactive, no-reboot, reboot, staged, try   apply config mode (default auto)",
+			want: TalosctlFlag{Name: "mode", Shorthand: "m", Type: "auto, interactive, no-reboot, reboot, staged, try", Default: "auto", Description: "apply config mode"},
+		},
+		{
+			name: "typeless boolean",
+			line: "      --dry-run                                                  check how the config change will be applied in dry-run mode",
+			want: TalosctlFlag{Name: "dry-run", Type: "bool", Description: "check how the config change will be applied in dry-run mode"},
+		},
+		{
+			name: "typeless boolean with shorthand",
+			line: "  -h, --help   help for cluster",
+			want: TalosctlFlag{Name: "help", Shorthand: "h", Type: "bool", Description: "help for cluster"},
+		},
+		{
+			name: "quoted default",
+			line: `      --talosconfig string   The path to the Talos configuration file (default "/var/run/secrets/talos.dev/config")`,
+			want: TalosctlFlag{Name: "talosconfig", Type: "string", Default: "/var/run/secrets/talos.dev/config", Description: "The path to the Talos configuration file"},
+		},
+		{
+			name: "defaults wording is not a default",
+			line: "      --cert-fingerprint strings   list of server certificate fingeprints to accept (defaults to no check)",
+			want: TalosctlFlag{Name: "cert-fingerprint", Type: "strings", Description: "list of server certificate fingeprints to accept (defaults to no check)"},
+		},
+		{
+			name: "parenthesis before the default",
+			line: "      --timeout duration   rolled back after timeout (if try mode is selected) (default 1m0s)",
+			want: TalosctlFlag{Name: "timeout", Type: "duration", Default: "1m0s", Description: "rolled back after timeout (if try mode is selected)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := parseFlags(tt.line)
+			if len(flags) != 1 {
+				t.Fatalf("parsed %d flags, want 1", len(flags))
+			}
+			if flags[0] != tt.want {
+				t.Errorf("parseFlags() = %+v, want %+v", flags[0], tt.want)
+			}
+		})
+	}
+
+	if flags := parseFlags("talosctl apply-config [flags]\n\nnot a flag\n"); len(flags) != 0 {
+		t.Errorf("parsed flags %+v from lines that aren't flags", flags)
+	}
+}
+
+func TestParseTalosctlCommands(t *testing.T) {
+	doc := loadTestDocument(t, "testdata/talosctl.md", "talos/v1.11/reference/cli", "CLI")
+	if !isCLIReferencePage(doc) {
+		t.Fatal("fixture is not recognised as the CLI reference")
+	}
+
+	commands := make(map[string]TalosctlCommand)
+	var order []string
+	for _, command := range parseTalosctlCommands(doc) {
+		commands[command.Name] = command
+		order = append(order, command.Name)
+	}
+	wantOrder := "talosctl apply-config,talosctl cluster create,talosctl cluster,talosctl image cache-create,talosctl"
+	if strings.Join(order, ",") != wantOrder {
+		t.Fatalf("parsed commands %v, want %s", order, wantOrder)
+	}
+
+	tests := []struct {
+		name        string
+		short       string
+		usage       string
+		examples    string
+		flags       []string
+		inherited   []string
+		parent      string
+		subcommands []string
+		firstLine   string
+		lastLine    string
+	}{
+		{
+			name:      "talosctl apply-config",
+			short:     "Apply a new configuration to a node",
+			flags:     []string{"cert-fingerprint", "dry-run", "file", "help", "mode", "timeout"},
+			inherited: []string{"endpoints", "nodes", "talosconfig"},
+			parent:    "talosctl",
+			firstLine: "## talosctl apply-config",
+			lastLine:  "* [talosctl](#talosctl)",
+		},
+		{
+			name:      "talosctl cluster create",
+			short:     "Creates a local docker-based or QEMU-based kubernetes cluster",
+			usage:     "talosctl cluster create [flags]",
+			examples:  "talosctl cluster create --workers 2",
+			flags:     []string{"workers"},
+			parent:    "talosctl cluster",
+			firstLine: "## talosctl cluster create",
+			lastLine:  "      --workers int",
+		},
+		{
+			name:        "talosctl cluster",
+			short:       "A collection of commands for managing local docker-based or QEMU-based clusters",
+			flags:       []string{"help"},
+			parent:      "talosctl",
+			subcommands: []string{"talosctl cluster create"},
+			firstLine:   "## talosctl cluster\n",
+			lastLine:    "  -h, --help   help for cluster",
+		},
+		{
+			// talosctl image isn't documented, so there is no parent
+			name:      "talosctl image cache-create",
+			short:     "Create a cache of images in OCI format into a directory",
+			firstLine: "## talosctl image cache-create",
+			lastLine:  "Create a cache of images",
+		},
+		{
+			name:        "talosctl",
+			short:       "A CLI for out-of-band management of Kubernetes nodes created by Talos",
+			subcommands: []string{"talosctl apply-config", "talosctl cluster"},
+			firstLine:   "## talosctl\n\nA CLI",
+			lastLine:    "A CLI for out-of-band management of Kubernetes nodes created by Talos\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := commands[tt.name]
+			if command.Short != tt.short || command.Usage != tt.usage || command.Examples != tt.examples {
+				t.Errorf("short %q usage %q examples %q, want %q %q %q", command.Short, command.Usage, command.Examples, tt.short, tt.usage, tt.examples)
+			}
+			if got := flagNames(command.Flags); got != strings.Join(tt.flags, ",") {
+				t.Errorf("flags %s, want %v", got, tt.flags)
+			}
+			if got := flagNames(command.InheritedFlags); got != strings.Join(tt.inherited, ",") {
+				t.Errorf("inherited flags %s, want %v", got, tt.inherited)
+			}
+			if command.Parent != tt.parent || strings.Join(command.Subcommands, ",") != strings.Join(tt.subcommands, ",") {
+				t.Errorf("parent %q subcommands %v, want %q %v", command.Parent, command.Subcommands, tt.parent, tt.subcommands)
+			}
+			if command.Flags == nil {
+				t.Error("flags are nil rather than empty")
+			}
+			first, last := lineOf(t, doc.Content, tt.firstLine), lineOf(t, doc.Content, tt.lastLine)
+			if command.StartLine != first || command.EndLine < last {
+				t.Errorf("spans lines %d-%d, want %d to at least %d", command.StartLine, command.EndLine, first, last)
+			}
+		})
+	}
+
+	if synopsis := commands["talosctl cluster create"].Synopsis; !strings.HasPrefix(synopsis, "Creates a local cluster for testing") {
+		t.Errorf("synopsis %q", synopsis)
+	}
+}
+
+func flagNames(flags []TalosctlFlag) string {
+	var names []string
+	for _, flag := range flags {
+		names = append(names, flag.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestDiffFlags(t *testing.T) {
+	from := []TalosctlFlag{
+		{Name: "mode", Shorthand: "m", Type: "auto, interactive, reboot", Default: "auto"},
+		{Name: "insecure", Shorthand: "i", Type: "bool"},
+		{Name: "timeout", Type: "duration", Default: "1m0s"},
+		{Name: "file", Shorthand: "f", Type: "string", Description: "the filename"},
+	}
+	to := []TalosctlFlag{
+		{Name: "mode", Shorthand: "m", Type: "auto, interactive, no-reboot, reboot, staged, try", Default: "auto"},
+		{Name: "timeout", Type: "duration", Default: "5m0s"},
+		{Name: "file", Shorthand: "f", Type: "string", Description: "the filename of the updated configuration"},
+		{Name: "dry-run", Type: "bool"},
+	}
+
+	want := []struct{ flag, change string }{
+		{"--dry-run", flagAdded},
+		{"--insecure", flagRemoved},
+		{"--mode", flagChanged},
+		{"--timeout", flagChanged},
+	}
+	changes := diffFlags(from, to)
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(want))
+	}
+	for i, w := range want {
+		change := changes[i]
+		if change.Flag != w.flag || change.Change != w.change {
+			t.Errorf("change %d = %s %s, want %s %s", i, change.Flag, change.Change, w.flag, w.change)
+		}
+		if (change.From == nil) != (w.change == flagAdded) || (change.To == nil) != (w.change == flagRemoved) {
+			t.Errorf("change %s %s has from %v to %v", change.Flag, change.Change, change.From, change.To)
+		}
+	}
+	if changes[3].From.Default != "1m0s" || changes[3].To.Default != "5m0s" {
+		t.Errorf("timeout change %+v -> %+v doesn't carry both defaults", changes[3].From, changes[3].To)
+	}
+
+	if changes := diffFlags(to, to); changes == nil || len(changes) != 0 {
+		t.Errorf("diffFlags of identical flags = %#v, want an empty list", changes)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	transportStdio = "stdio"
+	transportSSE   = "sse"
+	transportHTTP  = "http"
+)
+
+// streamableHTTPEndpoint is where the streamable HTTP transport accepts
+// requests. The SSE transport uses mcp-go's defaults, /sse and /message.
+const streamableHTTPEndpoint = "/mcp"
+
+func validateTransport(transport string) error {
+	switch transport {
+	case transportStdio, transportSSE, transportHTTP:
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q (expected %s, %s or %s)", transport, transportStdio, transportSSE, transportHTTP)
+	}
+}
+
+// serve runs the configured transport until it fails or Stop shuts it
+// down. Every HTTP client gets its own session on the shared MCP server, so
+// one index serves the whole team.
+func (s *TalosDocMCPServer) serve() error {
+	transport := s.config.Server.Transport
+	if transport == transportStdio {
+		log.Printf("==> Starting MCP Server on stdio...")
+		return server.ServeStdio(s.mcpServer)
+	}
+
+	httpServer := &http.Server{Addr: s.config.Server.Address}
+	if err := s.setupHTTPTransport(httpServer); err != nil {
+		return err
+	}
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve %s transport: %w", transport, err)
+	}
+	return nil
+}
+
+// setupHTTPTransport installs the SSE or streamable HTTP handler, behind API
+// key authentication, on httpServer without starting it.
+func (s *TalosDocMCPServer) setupHTTPTransport(httpServer *http.Server) error {
+	transport := s.config.Server.Transport
+	switch transport {
+	case transportSSE:
+		sseServer := server.NewSSEServer(s.mcpServer, server.WithHTTPServer(httpServer))
+		httpServer.Handler = sseServer
+		s.setShutdown(sseServer.Shutdown)
+		log.Printf("==> Starting MCP Server with SSE transport on %s (endpoints /sse and /message)", httpServer.Addr)
+	case transportHTTP:
+		httpTransport := server.NewStreamableHTTPServer(s.mcpServer,
+			server.WithEndpointPath(streamableHTTPEndpoint),
+			server.WithStreamableHTTPServer(httpServer),
+		)
+		httpServer.Handler = httpTransport
+		s.setShutdown(httpTransport.Shutdown)
+		log.Printf("==> Starting MCP Server with streamable HTTP transport on %s%s", httpServer.Addr, streamableHTTPEndpoint)
+	default:
+		return validateTransport(transport)
+	}
+
+	if len(s.apiKeys) > 0 {
+		httpServer.Handler = requireAPIKey(s.apiKeys, httpServer.Handler)
+		log.Printf("Requiring API keys (%d configured)", len(s.apiKeys))
+	} else {
+		log.Printf("WARNING: auth.allow_unauthenticated is set, %s transport is open to anyone who can reach %s", transport, httpServer.Addr)
+	}
+	return nil
+}
+
+func (s *TalosDocMCPServer) setShutdown(shutdown func(context.Context) error) {
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+	s.shutdownTransport = shutdown
+}
+
+// shutdown closes the HTTP transport's sessions and listener, if one is
+// running. Stdio has nothing to close; the process exits instead.
+func (s *TalosDocMCPServer) shutdown(ctx context.Context) error {
+	s.transportMu.Lock()
+	shutdown := s.shutdownTransport
+	s.shutdownTransport = nil
+	s.transportMu.Unlock()
+
+	if shutdown == nil {
+		return nil
+	}
+	return shutdown(ctx)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const testReadKey = "read-key"
+
+// newTestHTTPServer serves a server over the given transport from an
+// httptest listener, with a few indexed pages and a read-only API key.
+func newTestHTTPServer(t *testing.T, transportName string) *httptest.Server {
+	t.Helper()
+
+	searchEngine := openTestEngine(t, t.TempDir())
+	t.Cleanup(func() { searchEngine.Close() })
+	if err := searchEngine.IndexDocuments(testDocuments(3), "abc", nil); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+
+	config := &Config{}
+	config.Server.Name = "talos-docs-mcp"
+	config.Server.Version = "test"
+	config.Server.Transport = transportName
+	config.Auth.Keys = []APIKey{{Name: "reader", Key: testReadKey}}
+
+	s, err := newTalosDocMCPServer(config, nil, searchEngine)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	httpServer := &http.Server{}
+	if err := s.setupHTTPTransport(httpServer); err != nil {
+		t.Fatalf("failed to set up %s transport: %v", transportName, err)
+	}
+	ts := httptest.NewServer(httpServer.Handler)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.shutdown(ctx)
+		ts.Close()
+	})
+	return ts
+}
+
+func newTestClient(t *testing.T, transportName, baseURL, key string) (*client.Client, error) {
+	t.Helper()
+
+	headers := map[string]string{}
+	if key != "" {
+		headers["Authorization"] = "Bearer " + key
+	}
+
+	var c *client.Client
+	var err error
+	switch transportName {
+	case transportSSE:
+		c, err = client.NewSSEMCPClient(baseURL+"/sse", transport.WithHeaders(headers))
+	case transportHTTP:
+		c, err = client.NewStreamableHttpClient(baseURL+streamableHTTPEndpoint, transport.WithHTTPHeaders(headers))
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { c.Close() })
+
+	// The SSE stream lives as long as the context it is started with
+	if err := c.Start(context.Background()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "talos-mcp-test", Version: "test"}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func TestHTTPTransports(t *testing.T) {
+	for _, transportName := range []string{transportSSE, transportHTTP} {
+		t.Run(transportName, func(t *testing.T) {
+			ts := newTestHTTPServer(t, transportName)
+
+			if _, err := newTestClient(t, transportName, ts.URL, ""); err == nil {
+				t.Error("a client without an API key connected")
+			}
+			if _, err := newTestClient(t, transportName, ts.URL, "wrong-key"); err == nil {
+				t.Error("a client with an unknown API key connected")
+			}
+
+			c, err := newTestClient(t, transportName, ts.URL, testReadKey)
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+			if err != nil {
+				t.Fatalf("failed to list tools: %v", err)
+			}
+			listed := make(map[string]bool)
+			for _, tool := range tools.Tools {
+				listed[tool.Name] = true
+			}
+			if !listed["search_talos_docs"] || listed["sync_documentation"] {
+				t.Errorf("read key sees tools %v, want search_talos_docs without the admin tools", listed)
+			}
+
+			search := mcp.CallToolRequest{}
+			search.Params.Name = "search_talos_docs"
+			search.Params.Arguments = map[string]any{"query": "kubernetes"}
+			result, err := c.CallTool(ctx, search)
+			if err != nil {
+				t.Fatalf("failed to call search_talos_docs: %v", err)
+			}
+			if result.IsError || len(result.Content) == 0 {
+				t.Fatalf("search_talos_docs failed: %+v", result.Content)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "v1.11/talos/v1.11/page-") {
+				t.Errorf("search returned no indexed page: %s", text)
+			}
+
+			sync := mcp.CallToolRequest{}
+			sync.Params.Name = "sync_documentation"
+			if result, err := c.CallTool(ctx, sync); err == nil && !result.IsError {
+				t.Error("read key was allowed to call sync_documentation")
+			}
+		})
+	}
+}
+
+func TestHTTPTransportRequiresKeys(t *testing.T) {
+	searchEngine := openTestEngine(t, t.TempDir())
+	defer searchEngine.Close()
+
+	for _, tt := range []struct {
+		transport string
+		allow     bool
+		wantErr   bool
+	}{
+		{transport: transportStdio},
+		{transport: transportSSE, wantErr: true},
+		{transport: transportHTTP, wantErr: true},
+		{transport: transportHTTP, allow: true},
+	} {
+		config := &Config{}
+		config.Server.Transport = tt.transport
+		config.Auth.AllowUnauthenticated = tt.allow
+
+		_, err := newTalosDocMCPServer(config, nil, searchEngine)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s transport without keys (allow_unauthenticated=%v): error = %v, want error %v", tt.transport, tt.allow, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	upgradeStepBreaking = "breaking_change"
+	upgradeStepNote     = "upgrade_note"
+	upgradeStepGuide    = "upgrade_guide"
+	upgradeStepWhatsNew = "whats_new"
+
+	// upgradeSummaryLength caps the text quoted for each checklist step
+	upgradeSummaryLength = 300
+)
+
+var (
+	whatsNewKeywords = []string{"what's new", "whats-new", "what-s-new", "release-notes", "release notes"}
+	upgradeKeywords  = []string{"upgrad"}
+
+	breakingKeywords    = []string{"breaking", "deprecat", "removed", "removal", "incompatib", "no longer"}
+	upgradeNoteKeywords = []string{"upgrad", "migrat", "before you", "action required"}
+)
+
+// Citation points at the page, and optionally the section, a statement was
+// taken from.
+type Citation struct {
+	DocumentID string `json:"document_id"`
+	Title      string `json:"title"`
+	URI        string `json:"uri"`
+	URL        string `json:"url,omitempty"`
+	Section    string `json:"section,omitempty"`
+	Lines      string `json:"lines,omitempty"`
+}
+
+// UpgradeStep is one checklist item of an upgrade hop.
+type UpgradeStep struct {
+	Step     int      `json:"step"`
+	Category string   `json:"category"`
+	Title    string   `json:"title"`
+	Summary  string   `json:"summary,omitempty"`
+	Source   Citation `json:"source"`
+}
+
+// UpgradeHop is the upgrade from one indexed version to the next.
+type UpgradeHop struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Checklist []UpgradeStep `json:"checklist"`
+	Warnings  []string      `json:"warnings,omitempty"`
+}
+
+func (s *TalosDocMCPServer) handlePlanUpgrade(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fromVersion, err := request.RequireString("from_version")
+	if err != nil {
+		return mcp.NewToolResultError("from_version is required"), nil
+	}
+	toVersion, err := request.RequireString("to_version")
+	if err != nil {
+		return mcp.NewToolResultError("to_version is required"), nil
+	}
+
+	if fromVersion, err = s.resolveVersion(fromVersion); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("from_version: %v", err)), nil
+	}
+	if toVersion, err = s.resolveVersion(toVersion); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("to_version: %v", err)), nil
+	}
+	if compareVersions(fromVersion, toVersion) >= 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("to_version %s must be newer than from_version %s", toVersion, fromVersion)), nil
+	}
+
+	hops := s.planUpgrade(fromVersion, toVersion)
+	steps := 0
+	for _, hop := range hops {
+		steps += len(hop.Checklist)
+	}
+
+	result := map[string]interface{}{
+		"from_version": fromVersion,
+		"to_version":   toVersion,
+		"hops":         hops,
+		"total_steps":  steps,
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// planUpgrade walks every indexed version after from up to and including
+// to, oldest first. Talos upgrades go through each minor release, so each
+// hop lists what the target version's docs say to watch for.
+func (s *TalosDocMCPServer) planUpgrade(fromVersion, toVersion string) []*UpgradeHop {
+	documents := s.searchEngine.ListDocuments()
+
+	var hops []*UpgradeHop
+	previous := fromVersion
+	for _, version := range s.upgradeVersions(fromVersion, toVersion) {
+		hops = append(hops, upgradeHop(previous, version, documents))
+		previous = version
+	}
+	return hops
+}
+
+// upgradeVersions lists the indexed versions after from up to and including
+// to, oldest first: the versions an upgrade between them goes through.
+func (s *TalosDocMCPServer) upgradeVersions(fromVersion, toVersion string) []string {
+	var versions []string
+	for _, version := range s.getSortedVersions(s.searchEngine.GetTaxonomy().Versions) {
+		if compareVersions(version, fromVersion) > 0 && compareVersions(version, toVersion) <= 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// upgradeHop builds the checklist for upgrading to version from its
+// what's-new and upgrade pages: breaking changes first, then upgrade notes,
+// the upgrade guide itself and finally new features.
+func upgradeHop(fromVersion, toVersion string, documents []*Document) *UpgradeHop {
+	hop := &UpgradeHop{From: fromVersion, To: toVersion}
+
+	var breaking, notes, guides, features []UpgradeStep
+	var whatsNewPages, upgradePages int
+	for _, doc := range documents {
+		if doc.Version != toVersion {
+			continue
+		}
+
+		whatsNew := matchesAny(doc.Path+" "+doc.Title, whatsNewKeywords)
+		upgrade := !whatsNew && matchesAny(doc.Path+" "+doc.Title, upgradeKeywords)
+		if !whatsNew && !upgrade {
+			continue
+		}
+		if whatsNew {
+			whatsNewPages++
+		} else {
+			upgradePages++
+			guides = append(guides, UpgradeStep{
+				Category: upgradeStepGuide,
+				Title:    "Follow " + doc.Title,
+				Source:   documentCitation(doc, nil),
+			})
+		}
+
+		pageTitle := ""
+		for _, section := range splitSections(doc.Content) {
+			// A level 1 heading is the page title; classifying by it would
+			// put every section of "Upgrading Talos" under upgrade notes
+			if section.Level == 1 {
+				pageTitle = section.Heading
+				continue
+			}
+			summary := sectionSummary(section.Body, upgradeSummaryLength)
+			if section.Level == 0 || summary == "" {
+				continue
+			}
+			trail := strings.TrimPrefix(section.Trail, pageTitle+" > ")
+
+			step := UpgradeStep{
+				Title:   section.Heading,
+				Summary: summary,
+				Source:  documentCitation(doc, &section),
+			}
+			switch {
+			case matchesAny(trail, breakingKeywords):
+				step.Category = upgradeStepBreaking
+				breaking = append(breaking, step)
+			case matchesAny(trail, upgradeNoteKeywords):
+				step.Category = upgradeStepNote
+				notes = append(notes, step)
+			case whatsNew:
+				step.Category = upgradeStepWhatsNew
+				features = append(features, step)
+			}
+		}
+	}
+
+	if whatsNewPages == 0 {
+		hop.Warnings = append(hop.Warnings, fmt.Sprintf("no what's new page is indexed for %s", toVersion))
+	}
+	if upgradePages == 0 {
+		hop.Warnings = append(hop.Warnings, fmt.Sprintf("no upgrade guide is indexed for %s", toVersion))
+	}
+
+	for _, steps := range [][]UpgradeStep{breaking, notes, guides, features} {
+		for _, step := range steps {
+			step.Step = len(hop.Checklist) + 1
+			hop.Checklist = append(hop.Checklist, step)
+		}
+	}
+	return hop
+}
+
+// documentCitation cites a page, or one of its sections if given.
+func documentCitation(doc *Document, section *markdownSection) Citation {
+	citation := Citation{
+		DocumentID: doc.ID,
+		Title:      doc.Title,
+		URI:        documentResourceURI(doc),
+		URL:        doc.URL,
+	}
+	if section != nil {
+		citation.URL = sectionURL(doc, section.Heading)
+		citation.Section = section.Trail
+		citation.Lines = fmt.Sprintf("%d-%d", section.StartLine, section.EndLine)
+	}
+	return citation
+}
+
+// sectionSummary flattens a section's text, without its heading and code
+// blocks, into at most limit characters.
+func sectionSummary(body string, limit int) string {
+	var words []string
+	inFence := false
+	for i, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || (i == 0 && strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		words = append(words, strings.Fields(trimmed)...)
+	}
+
+	summary := strings.Join(words, " ")
+	if len(summary) <= limit {
+		return summary
+	}
+	cut := strings.LastIndex(summary[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return summary[:cut] + "..."
+}
+
+func matchesAny(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
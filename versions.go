@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseVersion splits a version such as v1.11 or v1.11.2 into its numeric
+// parts. It fails for anything else, e.g. "latest".
+func parseVersion(version string) ([]int, bool) {
+	fields := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	parts := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// compareVersions orders versions numerically, so v1.9 comes before v1.11.
+// Versions that don't parse sort after those that do, lexically among
+// themselves.
+func compareVersions(a, b string) int {
+	aParts, aOK := parseVersion(a)
+	bParts, bOK := parseVersion(b)
+	switch {
+	case aOK && !bOK:
+		return -1
+	case !aOK && bOK:
+		return 1
+	case !aOK && !bOK:
+		return strings.Compare(a, b)
+	}
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := 0, 0
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// sortVersions sorts versions oldest first.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+}